@@ -1,9 +0,0 @@
-class ChatCounterWorker
-    include Sidekiq::Worker
-  
-    def perform(application_token)
-      Application.where(token: application_token)
-                 .update_all('chats_count = chats_count + 1')
-    end
-  end
-  
\ No newline at end of file
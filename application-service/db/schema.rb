@@ -34,6 +34,7 @@ ActiveRecord::Schema[7.1].define(version: 0) do
     t.text "body", null: false
     t.timestamp "created_at", null: false
     t.index ["chat_id", "number"], name: "unique_chat_number", unique: true
+    t.index ["body"], name: "ft_body", type: :fulltext
   end
 
   # Add foreign key constraint referencing token in applications
@@ -0,0 +1,322 @@
+// Command indexcheck compares the messages index with the messages table.
+// It first compares every chat's message count, highest number and sum of
+// numbers on both sides, then lists the individual documents of mismatched
+// chats that are missing from the index or no longer exist in MySQL. The
+// findings are written as a JSON report. Nothing is changed unless -repair
+// is given, in which case missing messages are indexed and orphaned
+// documents deleted.
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "flag"
+    "fmt"
+    "log"
+    "os"
+    "os/signal"
+    "sort"
+    "syscall"
+    "time"
+
+    "go.uber.org/zap"
+
+    "chat-service/config"
+    "chat-service/internal/repository/mysql"
+    "chat-service/pkg/database"
+    "chat-service/pkg/elasticsearch"
+)
+
+// repairBatchSize bounds the number of documents per repair request.
+const repairBatchSize = 500
+
+// Report is the JSON document written by indexcheck.
+type Report struct {
+    Index           string         `json:"index"`
+    StartedAt       time.Time      `json:"started_at"`
+    FinishedAt      time.Time      `json:"finished_at"`
+    DryRun          bool           `json:"dry_run"`
+    ChatsChecked    int            `json:"chats_checked"`
+    ChatsMismatched int            `json:"chats_mismatched"`
+    MissingTotal    int            `json:"missing_total"`
+    OrphanedTotal   int            `json:"orphaned_total"`
+    Reindexed       int            `json:"reindexed"`
+    Deleted         int            `json:"deleted"`
+    StillStored     int            `json:"still_stored"`
+    Chats           []ChatMismatch `json:"chats"`
+}
+
+// ChatMismatch describes one chat whose documents differ from its rows.
+type ChatMismatch struct {
+    ChatID         uint64   `json:"chat_id"`
+    DBCount        int64    `json:"db_count"`
+    IndexCount     int64    `json:"index_count"`
+    DBMaxNumber    int      `json:"db_max_number"`
+    IndexMaxNumber int      `json:"index_max_number"`
+    Missing        []uint64 `json:"missing"`
+    Orphaned       []uint64 `json:"orphaned"`
+}
+
+func main() {
+    repair := flag.Bool("repair", false, "index missing messages and delete orphaned documents (default is a dry run)")
+    output := flag.String("output", "-", "file to write the JSON report to, - for stdout")
+    flag.Parse()
+
+    logger, err := zap.NewProduction()
+    if err != nil {
+        log.Fatalf("Failed to create logger: %v", err)
+    }
+    defer logger.Sync()
+
+    cfg, err := config.Load()
+    if err != nil {
+        logger.Fatal("Error loading configuration", zap.Error(err))
+    }
+
+    db, err := database.NewMySQLConnection(database.MySQLConfig{
+        Host:     cfg.MySQL.Host,
+        Port:     cfg.MySQL.Port,
+        User:     cfg.MySQL.User,
+        Password: cfg.MySQL.Password,
+        Database: cfg.MySQL.Database,
+    })
+    if err != nil {
+        logger.Fatal("Failed to connect to MySQL", zap.Error(err))
+    }
+    defer db.Close()
+
+    esClient, err := elasticsearch.NewClient(elasticsearch.Config{
+        URL: cfg.Elasticsearch.URL,
+    })
+    if err != nil {
+        logger.Fatal("Failed to connect to Elasticsearch", zap.Error(err))
+    }
+
+    ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+    defer stop()
+
+    checker := &checker{
+        // Only the MySQL reads are used, so no bulk indexer is needed.
+        messages: mysql.NewMessageRepository(db, esClient, nil),
+        es:       esClient,
+        index:    elasticsearch.MessagesAlias,
+        logger:   logger,
+    }
+
+    report, err := checker.check(ctx)
+    if err != nil {
+        logger.Fatal("Consistency check failed", zap.Error(err))
+    }
+
+    if *repair {
+        report.DryRun = false
+        if err := checker.repair(ctx, report); err != nil {
+            logger.Error("Repair stopped early", zap.Error(err))
+        }
+    }
+    report.FinishedAt = time.Now().UTC()
+
+    if err := writeReport(report, *output); err != nil {
+        logger.Fatal("Failed to write report", zap.Error(err))
+    }
+
+    logger.Info("Consistency check complete",
+        zap.Int("chats_checked", report.ChatsChecked),
+        zap.Int("chats_mismatched", report.ChatsMismatched),
+        zap.Int("missing", report.MissingTotal),
+        zap.Int("orphaned", report.OrphanedTotal),
+        zap.Int("reindexed", report.Reindexed),
+        zap.Int("deleted", report.Deleted),
+        zap.Int("still_stored", report.StillStored))
+}
+
+type checker struct {
+    messages *mysql.MessageRepository
+    es       *elasticsearch.Client
+    index    string
+    logger   *zap.Logger
+}
+
+// check compares per-chat stats and lists the differing documents of every
+// chat whose stats disagree.
+func (c *checker) check(ctx context.Context) (*Report, error) {
+    report := &Report{
+        Index:     c.index,
+        StartedAt: time.Now().UTC(),
+        DryRun:    true,
+        Chats:     []ChatMismatch{},
+    }
+
+    indexed, err := c.es.MessageStatsByChat(c.index)
+    if err != nil {
+        return nil, err
+    }
+
+    var suspects []ChatMismatch
+    err = c.messages.EachChatStats(ctx, func(chatID uint64, count int64, maxNumber int, numberSum int64) error {
+        report.ChatsChecked++
+
+        stats, ok := indexed[chatID]
+        delete(indexed, chatID)
+        if ok && stats.Count == count && stats.MaxNumber == maxNumber && stats.NumberSum == numberSum {
+            return nil
+        }
+
+        suspects = append(suspects, ChatMismatch{
+            ChatID:         chatID,
+            DBCount:        count,
+            IndexCount:     stats.Count,
+            DBMaxNumber:    maxNumber,
+            IndexMaxNumber: stats.MaxNumber,
+        })
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    // Chats left over have documents but no rows at all.
+    for chatID, stats := range indexed {
+        report.ChatsChecked++
+        suspects = append(suspects, ChatMismatch{
+            ChatID:         chatID,
+            IndexCount:     stats.Count,
+            IndexMaxNumber: stats.MaxNumber,
+        })
+    }
+    sort.Slice(suspects, func(i, j int) bool { return suspects[i].ChatID < suspects[j].ChatID })
+
+    for _, mismatch := range suspects {
+        if err := ctx.Err(); err != nil {
+            return nil, err
+        }
+
+        // Documents are read first: a message written in between then
+        // shows up as missing, which reindexing fixes harmlessly, rather
+        // than as orphaned.
+        documents, err := c.es.MessageIDsOfChat(c.index, mismatch.ChatID)
+        if err != nil {
+            return nil, err
+        }
+        rows, err := c.messages.IDsByChat(ctx, mismatch.ChatID)
+        if err != nil {
+            return nil, err
+        }
+
+        mismatch.Missing, mismatch.Orphaned = diff(rows, documents)
+        if len(mismatch.Missing) == 0 && len(mismatch.Orphaned) == 0 {
+            continue
+        }
+
+        report.ChatsMismatched++
+        report.MissingTotal += len(mismatch.Missing)
+        report.OrphanedTotal += len(mismatch.Orphaned)
+        report.Chats = append(report.Chats, mismatch)
+    }
+
+    return report, nil
+}
+
+// repair indexes the missing messages and deletes the orphaned documents
+// listed in report. Orphans are looked up in MySQL once more right before
+// deleting, so a message stored since the check keeps its document.
+func (c *checker) repair(ctx context.Context, report *Report) error {
+    var missing, orphaned []uint64
+    for _, mismatch := range report.Chats {
+        missing = append(missing, mismatch.Missing...)
+        orphaned = append(orphaned, mismatch.Orphaned...)
+    }
+
+    for start := 0; start < len(missing); start += repairBatchSize {
+        if err := ctx.Err(); err != nil {
+            return err
+        }
+
+        batch, err := c.messages.ListByIDs(ctx, missing[start:min(start+repairBatchSize, len(missing))])
+        if err != nil {
+            return err
+        }
+
+        documents := make(map[string]interface{}, len(batch))
+        for _, document := range batch {
+            documents[fmt.Sprintf("%d", document.ID)] = document
+        }
+        if err := c.es.BulkLoad(c.index, documents); err != nil {
+            return err
+        }
+        report.Reindexed += len(documents)
+    }
+
+    for start := 0; start < len(orphaned); start += repairBatchSize {
+        if err := ctx.Err(); err != nil {
+            return err
+        }
+
+        batch := orphaned[start:min(start+repairBatchSize, len(orphaned))]
+        stored, err := c.messages.ListByIDs(ctx, batch)
+        if err != nil {
+            return err
+        }
+        keep := make(map[uint64]bool, len(stored))
+        for _, document := range stored {
+            keep[document.ID] = true
+        }
+        report.StillStored += len(keep)
+
+        ids := make([]string, 0, len(batch))
+        for _, id := range batch {
+            if !keep[id] {
+                ids = append(ids, fmt.Sprintf("%d", id))
+            }
+        }
+        if len(ids) == 0 {
+            continue
+        }
+        if err := c.es.BulkDelete(c.index, ids); err != nil {
+            return err
+        }
+        report.Deleted += len(ids)
+    }
+
+    return c.es.Refresh(c.index)
+}
+
+// diff returns the ids only in rows and the ids only in documents. Both
+// slices must be sorted.
+func diff(rows []uint64, documents []uint64) (missing []uint64, orphaned []uint64) {
+    missing, orphaned = []uint64{}, []uint64{}
+
+    i, j := 0, 0
+    for i < len(rows) && j < len(documents) {
+        switch {
+        case rows[i] == documents[j]:
+            i++
+            j++
+        case rows[i] < documents[j]:
+            missing = append(missing, rows[i])
+            i++
+        default:
+            orphaned = append(orphaned, documents[j])
+            j++
+        }
+    }
+    missing = append(missing, rows[i:]...)
+    orphaned = append(orphaned, documents[j:]...)
+    return missing, orphaned
+}
+
+func writeReport(report *Report, path string) error {
+    out := os.Stdout
+    if path != "-" {
+        file, err := os.Create(path)
+        if err != nil {
+            return err
+        }
+        defer file.Close()
+        out = file
+    }
+
+    encoder := json.NewEncoder(out)
+    encoder.SetIndent("", "  ")
+    return encoder.Encode(report)
+}
@@ -0,0 +1,297 @@
+// Command reindex rebuilds the messages search index from MySQL without
+// downtime. It streams the messages table in id order into a new versioned
+// index (messages_v<N+1> by default), then atomically points the messages
+// alias at it and loads the rows inserted in the meantime. The id of the
+// last loaded message is checkpointed in Redis after every batch, so running
+// the command again after an interruption resumes where it stopped, also
+// when the catch-up pass after the swap was interrupted; -restart drops the
+// partial index and starts over.
+//
+// Edits and deletions made while the first pass runs are applied to the old
+// index only. Run it in a quiet period, or follow it with a consistency
+// check.
+package main
+
+import (
+    "context"
+    "flag"
+    "fmt"
+    "log"
+    "os/signal"
+    "regexp"
+    "strconv"
+    "syscall"
+    "time"
+
+    "go.uber.org/zap"
+
+    "chat-service/config"
+    "chat-service/internal/repository/mysql"
+    "chat-service/internal/repository/redis"
+    "chat-service/pkg/database"
+    "chat-service/pkg/elasticsearch"
+)
+
+const progressInterval = 5 * time.Second
+
+var versionedIndex = regexp.MustCompile(`^` + elasticsearch.MessagesAlias + `_v(\d+)$`)
+
+func main() {
+    index := flag.String("index", "", "name of the new index (default: next version after the current one)")
+    batchSize := flag.Int("batch", 1000, "messages per bulk request")
+    restart := flag.Bool("restart", false, "drop a partially loaded index and its checkpoint before starting")
+    flag.Parse()
+
+    logger, err := zap.NewProduction()
+    if err != nil {
+        log.Fatalf("Failed to create logger: %v", err)
+    }
+    defer logger.Sync()
+
+    cfg, err := config.Load()
+    if err != nil {
+        logger.Fatal("Error loading configuration", zap.Error(err))
+    }
+
+    db, err := database.NewMySQLConnection(database.MySQLConfig{
+        Host:     cfg.MySQL.Host,
+        Port:     cfg.MySQL.Port,
+        User:     cfg.MySQL.User,
+        Password: cfg.MySQL.Password,
+        Database: cfg.MySQL.Database,
+    })
+    if err != nil {
+        logger.Fatal("Failed to connect to MySQL", zap.Error(err))
+    }
+    defer db.Close()
+
+    redisClient, err := database.NewRedisConnection(database.RedisConfig{
+        Host: cfg.Redis.Host,
+        Port: cfg.Redis.Port,
+    })
+    if err != nil {
+        logger.Fatal("Failed to connect to Redis", zap.Error(err))
+    }
+    defer redisClient.Close()
+
+    esClient, err := elasticsearch.NewClient(elasticsearch.Config{
+        URL: cfg.Elasticsearch.URL,
+    })
+    if err != nil {
+        logger.Fatal("Failed to connect to Elasticsearch", zap.Error(err))
+    }
+
+    ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+    defer stop()
+
+    current, err := esClient.AliasTarget(elasticsearch.MessagesAlias)
+    if err != nil {
+        logger.Fatal("Failed to resolve messages alias", zap.Error(err))
+    }
+    legacy := false
+    if current == "" {
+        legacy, err = esClient.IndexExists(elasticsearch.MessagesAlias)
+        if err != nil {
+            logger.Fatal("Failed to check for a legacy messages index", zap.Error(err))
+        }
+    }
+
+    reindexer := &reindexer{
+        // Only the MySQL reads are used, so no bulk indexer is needed.
+        messages:   mysql.NewMessageRepository(db, esClient, nil),
+        es:         esClient,
+        checkpoint: redis.NewReindexCheckpoint(redisClient),
+        batchSize:  *batchSize,
+        logger:     logger,
+    }
+
+    // The checkpoint is cleared once the catch-up pass finishes, so one left
+    // on the index the alias points at means that pass was interrupted after
+    // the swap. Finish it rather than starting yet another index.
+    if current != "" && !*restart && (*index == "" || *index == current) {
+        lastID, err := reindexer.checkpoint.Load(ctx, current)
+        if err != nil {
+            logger.Fatal("Failed to load checkpoint", zap.Error(err))
+        }
+        if lastID > 0 {
+            logger.Info("Resuming catch-up pass",
+                zap.String("index", current),
+                zap.Uint64("last_id", lastID))
+            reindexer.index = current
+            reindexer.catchUp(ctx)
+            logger.Info("Reindex complete", zap.String("index", current))
+            return
+        }
+    }
+
+    target := *index
+    if target == "" {
+        target = nextIndex(current)
+    }
+    if target == current || target == elasticsearch.MessagesAlias {
+        logger.Fatal("Refusing to reindex into the live index", zap.String("index", target))
+    }
+    reindexer.index = target
+
+    if *restart {
+        if err := esClient.DeleteIndex(target); err != nil {
+            logger.Fatal("Failed to drop partial index", zap.Error(err))
+        }
+        if err := reindexer.checkpoint.Clear(ctx, target); err != nil {
+            logger.Fatal("Failed to clear checkpoint", zap.Error(err))
+        }
+    }
+
+    exists, err := esClient.IndexExists(target)
+    if err != nil {
+        logger.Fatal("Failed to check target index", zap.Error(err))
+    }
+    if !exists {
+        if err := esClient.CreateMessagesIndex(target); err != nil {
+            logger.Fatal("Failed to create target index", zap.Error(err))
+        }
+        // A checkpoint without its index belongs to a dropped attempt.
+        if err := reindexer.checkpoint.Clear(ctx, target); err != nil {
+            logger.Fatal("Failed to clear checkpoint", zap.Error(err))
+        }
+    }
+
+    logger.Info("Reindexing messages",
+        zap.String("from", current),
+        zap.Bool("legacy", legacy),
+        zap.String("to", target))
+
+    if err := esClient.SetRefreshInterval(target, "-1"); err != nil {
+        logger.Fatal("Failed to disable refresh", zap.Error(err))
+    }
+    if err := reindexer.load(ctx); err != nil {
+        logger.Fatal("Reindex interrupted, run again to resume", zap.Error(err))
+    }
+    if err := esClient.SetRefreshInterval(target, "1s"); err != nil {
+        logger.Fatal("Failed to restore refresh", zap.Error(err))
+    }
+    if err := esClient.Refresh(target); err != nil {
+        logger.Fatal("Failed to refresh target index", zap.Error(err))
+    }
+
+    if legacy {
+        err = esClient.ReplaceIndexWithAlias(elasticsearch.MessagesAlias, target)
+    } else {
+        err = esClient.SwapAlias(elasticsearch.MessagesAlias, target)
+    }
+    if err != nil {
+        logger.Fatal("Failed to swap messages alias", zap.Error(err))
+    }
+    logger.Info("Swapped messages alias", zap.String("index", target))
+
+    // Messages created during the first pass went to the old index; the
+    // alias now sends new writes here, so one more pass closes the gap.
+    reindexer.catchUp(ctx)
+
+    logger.Info("Reindex complete",
+        zap.String("index", target),
+        zap.String("previous", current))
+}
+
+// nextIndex returns the versioned index that follows current.
+func nextIndex(current string) string {
+    version := 0
+    if match := versionedIndex.FindStringSubmatch(current); match != nil {
+        version, _ = strconv.Atoi(match[1])
+    }
+    return fmt.Sprintf("%s_v%d", elasticsearch.MessagesAlias, version+1)
+}
+
+type reindexer struct {
+    messages   *mysql.MessageRepository
+    es         *elasticsearch.Client
+    checkpoint *redis.ReindexCheckpoint
+    index      string
+    batchSize  int
+    logger     *zap.Logger
+}
+
+// catchUp loads the messages inserted since the checkpoint into the index
+// the alias already points at, then clears the checkpoint. An interrupted
+// catch-up keeps its checkpoint, which the next run picks up.
+func (r *reindexer) catchUp(ctx context.Context) {
+    if err := r.load(ctx); err != nil {
+        r.logger.Fatal("Catch-up pass interrupted, run again to resume", zap.Error(err))
+    }
+    if err := r.es.Refresh(r.index); err != nil {
+        r.logger.Fatal("Failed to refresh target index", zap.Error(err))
+    }
+    if err := r.checkpoint.Clear(ctx, r.index); err != nil {
+        r.logger.Fatal("Failed to clear checkpoint", zap.Error(err))
+    }
+}
+
+// load bulk-loads every message after the checkpoint, saving the checkpoint
+// after each batch, and reports progress and throughput along the way.
+func (r *reindexer) load(ctx context.Context) error {
+    lastID, err := r.checkpoint.Load(ctx, r.index)
+    if err != nil {
+        return err
+    }
+    maxID, err := r.messages.MaxID(ctx)
+    if err != nil {
+        return err
+    }
+
+    started := time.Now()
+    lastReport := started
+    loaded := 0
+
+    for {
+        if err := ctx.Err(); err != nil {
+            return err
+        }
+
+        batch, err := r.messages.ListAfterID(ctx, lastID, r.batchSize)
+        if err != nil {
+            return err
+        }
+        if len(batch) == 0 {
+            break
+        }
+
+        documents := make(map[string]interface{}, len(batch))
+        for _, message := range batch {
+            documents[fmt.Sprintf("%d", message.ID)] = message
+        }
+        if err := r.es.BulkLoad(r.index, documents); err != nil {
+            return err
+        }
+
+        lastID = batch[len(batch)-1].ID
+        loaded += len(batch)
+        if err := r.checkpoint.Save(ctx, r.index, lastID); err != nil {
+            return err
+        }
+
+        if time.Since(lastReport) >= progressInterval {
+            lastReport = time.Now()
+            r.report("Reindexing messages", loaded, lastID, maxID, started)
+        }
+    }
+
+    r.report("Loaded messages", loaded, lastID, maxID, started)
+    return nil
+}
+
+func (r *reindexer) report(msg string, loaded int, lastID uint64, maxID uint64, started time.Time) {
+    elapsed := time.Since(started)
+    progress := 100.0
+    if maxID > 0 && lastID < maxID {
+        progress = float64(lastID) * 100 / float64(maxID)
+    }
+
+    r.logger.Info(msg,
+        zap.String("index", r.index),
+        zap.Int("messages", loaded),
+        zap.Uint64("last_id", lastID),
+        zap.Uint64("max_id", maxID),
+        zap.String("progress", fmt.Sprintf("%.1f%%", progress)),
+        zap.Float64("messages_per_second", float64(loaded)/elapsed.Seconds()),
+        zap.Duration("elapsed", elapsed))
+}
@@ -0,0 +1,95 @@
+// Command reseed rebuilds every Redis chat and message sequence key from the
+// highest numbers stored in MySQL. By default counters are only raised, so
+// it is safe to run while chat-service is serving traffic; -force overwrites
+// them with the MySQL values and should only be used with writes stopped.
+package main
+
+import (
+    "context"
+    "flag"
+    "log"
+    "time"
+
+    "go.uber.org/zap"
+
+    "chat-service/config"
+    "chat-service/internal/repository/mysql"
+    "chat-service/internal/repository/redis"
+    "chat-service/pkg/database"
+)
+
+func main() {
+    force := flag.Bool("force", false, "overwrite sequence keys instead of only raising them")
+    flag.Parse()
+
+    logger, err := zap.NewProduction()
+    if err != nil {
+        log.Fatalf("Failed to create logger: %v", err)
+    }
+    defer logger.Sync()
+
+    cfg, err := config.Load()
+    if err != nil {
+        logger.Fatal("Error loading configuration", zap.Error(err))
+    }
+
+    db, err := database.NewMySQLConnection(database.MySQLConfig{
+        Host:     cfg.MySQL.Host,
+        Port:     cfg.MySQL.Port,
+        User:     cfg.MySQL.User,
+        Password: cfg.MySQL.Password,
+        Database: cfg.MySQL.Database,
+    })
+    if err != nil {
+        logger.Fatal("Failed to connect to MySQL", zap.Error(err))
+    }
+    defer db.Close()
+
+    redisClient, err := database.NewRedisConnection(database.RedisConfig{
+        Host: cfg.Redis.Host,
+        Port: cfg.Redis.Port,
+    })
+    if err != nil {
+        logger.Fatal("Failed to connect to Redis", zap.Error(err))
+    }
+    defer redisClient.Close()
+
+    source := mysql.NewSequenceSource(db)
+    sequenceRepo := redis.NewSequenceRepository(redisClient, source)
+
+    ctx := context.Background()
+    started := time.Now()
+
+    chats := 0
+    err = source.EachChatSequence(ctx, func(applicationToken string, max int) error {
+        chats++
+        if *force {
+            return sequenceRepo.SetChatSequence(ctx, applicationToken, max)
+        }
+        return sequenceRepo.RaiseChatSequence(ctx, applicationToken, max)
+    })
+    if err != nil {
+        logger.Fatal("Failed to rebuild chat sequences", zap.Error(err))
+    }
+    logger.Info("Rebuilt chat sequences", zap.Int("applications", chats))
+
+    messages := 0
+    err = source.EachMessageSequence(ctx, func(chatID uint64, max int) error {
+        messages++
+        if messages%10000 == 0 {
+            logger.Info("Rebuilding message sequences", zap.Int("chats", messages))
+        }
+        if *force {
+            return sequenceRepo.SetMessageSequence(ctx, chatID, max)
+        }
+        return sequenceRepo.RaiseMessageSequence(ctx, chatID, max)
+    })
+    if err != nil {
+        logger.Fatal("Failed to rebuild message sequences", zap.Error(err))
+    }
+
+    logger.Info("Rebuilt message sequences",
+        zap.Int("chats", messages),
+        zap.Bool("force", *force),
+        zap.Duration("elapsed", time.Since(started)))
+}
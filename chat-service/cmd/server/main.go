@@ -15,12 +15,14 @@ import (
     _ "chat-service/docs"
 
     "chat-service/config"
+    "chat-service/internal/consumer"
     "chat-service/internal/handler"
     "chat-service/internal/repository/mysql"
     "chat-service/internal/repository/redis"
     "chat-service/internal/service"
     "chat-service/pkg/database"
     "chat-service/pkg/elasticsearch"
+    "chat-service/pkg/events"
     "chat-service/pkg/rabbitmq"
 )
 
@@ -29,6 +31,9 @@ import (
 // @description A service for managing chats and messages
 // @host localhost:8080
 // @BasePath /
+// @securityDefinitions.apikey AdminToken
+// @in header
+// @name Authorization
 func main() {
 
     logger, err := zap.NewProduction()
@@ -64,54 +69,214 @@ func main() {
     }
     defer redisClient.Close()
 
-    rabbitMQ, err := rabbitmq.NewClient(rabbitmq.Config{
-        Host:     cfg.RabbitMQ.Host,
-        Port:     cfg.RabbitMQ.Port,
-        User:     cfg.RabbitMQ.User,
-        Password: cfg.RabbitMQ.Password,
+    // Every open event feed holds a connection in a blocking XREAD, so the
+    // feeds get their own pool and cannot starve the rest of the service.
+    feedRedisClient, err := database.NewRedisConnection(database.RedisConfig{
+        Host:     cfg.Redis.Host,
+        Port:     cfg.Redis.Port,
+        PoolSize: cfg.Redis.FeedPoolSize,
     })
     if err != nil {
-        logger.Fatal("Failed to connect to RabbitMQ", zap.Error(err))
+        logger.Fatal("Failed to connect to Redis", zap.Error(err))
+    }
+    defer feedRedisClient.Close()
+
+    // The outbox relay publishes to RabbitMQ, or with EVENT_BUS=memory to an
+    // in-process bus so the service runs without a broker.
+    var publisher service.EventPublisher
+    var rabbitMQ *rabbitmq.Client
+    var memoryBus *events.MemoryBus
+    switch cfg.EventBus.Driver {
+    case config.EventBusRabbitMQ:
+        queueBindings, err := rabbitmq.ParseQueueBindings(cfg.RabbitMQ.Queues)
+        if err != nil {
+            logger.Fatal("Invalid RabbitMQ queue configuration", zap.Error(err))
+        }
+
+        rabbitMQ, err = rabbitmq.NewClient(rabbitmq.Config{
+            Host:                cfg.RabbitMQ.Host,
+            Port:                cfg.RabbitMQ.Port,
+            User:                cfg.RabbitMQ.User,
+            Password:            cfg.RabbitMQ.Password,
+            ReconnectMinBackoff: cfg.RabbitMQ.ReconnectMinBackoff,
+            ReconnectMaxBackoff: cfg.RabbitMQ.ReconnectMaxBackoff,
+            PublishTimeout:      cfg.RabbitMQ.PublishTimeout,
+            BufferSize:          cfg.RabbitMQ.BufferSize,
+            Topology: rabbitmq.Topology{
+                Queues:             queueBindings,
+                RetryExchange:      cfg.RabbitMQ.RetryExchange,
+                DeadLetterExchange: cfg.RabbitMQ.DeadLetterExchange,
+                RetryDelay:         cfg.RabbitMQ.RetryDelay,
+                MaxRetries:         cfg.RabbitMQ.MaxRetries,
+            },
+        })
+        if err != nil {
+            logger.Fatal("Failed to connect to RabbitMQ", zap.Error(err))
+        }
+        defer rabbitMQ.Close()
+        publisher = rabbitMQ
+    case config.EventBusMemory:
+        memoryBus = events.NewMemoryBus(cfg.EventBus.MemoryBuffer)
+        publisher = memoryBus
+
+        // Log in-process events so they can be followed during development.
+        subscription := memoryBus.Subscribe()
+        defer subscription.Close()
+        go func() {
+            for envelope := range subscription.Events() {
+                logger.Info("event published",
+                    zap.String("event_id", envelope.ID),
+                    zap.String("event_type", envelope.Type),
+                    zap.String("application_token", envelope.ApplicationToken))
+            }
+        }()
+        logger.Info("Using the in-memory event bus, events do not leave this process")
+    default:
+        logger.Fatal("Unknown event bus", zap.String("EVENT_BUS", cfg.EventBus.Driver))
     }
-    defer rabbitMQ.Close()
 
     esClient, err := elasticsearch.NewClient(elasticsearch.Config{
-        URL: cfg.Elasticsearch.URL,
+        URL:              cfg.Elasticsearch.URL,
+        BreakerThreshold: cfg.Elasticsearch.BreakerThreshold,
+        BreakerCooldown:  cfg.Elasticsearch.BreakerCooldown,
     })
     if err != nil {
         logger.Fatal("Failed to connect to Elasticsearch", zap.Error(err))
     }
 
+    esIndexer := elasticsearch.NewBulkIndexer(esClient, elasticsearch.BulkIndexerConfig{
+        BatchSize:     cfg.Elasticsearch.BulkSize,
+        FlushInterval: cfg.Elasticsearch.BulkFlush,
+        QueueSize:     cfg.Elasticsearch.BulkQueueSize,
+        MaxRetries:    cfg.Elasticsearch.BulkMaxRetries,
+    })
+
     chatRepo := mysql.NewChatRepository(db)
-    messageRepo := mysql.NewMessageRepository(db, esClient)
-    sequenceRepo := redis.NewSequenceRepository(redisClient)
+    messageRepo := mysql.NewMessageRepository(db, esClient, esIndexer)
+    sequenceRepo := redis.NewSequenceRepository(redisClient, mysql.NewSequenceSource(db))
+    outboxRepo := mysql.NewOutboxRepository(db)
+    applicationRepo := mysql.NewApplicationRepository(db)
+    applicationCache := redis.NewApplicationCache(redisClient, cfg.Redis.ApplicationCacheTTL)
+
+    applicationService := service.NewApplicationService(
+        applicationRepo,
+        applicationCache,
+        logger,
+    )
+
+    idempotencyService := service.NewIdempotencyService(
+        redis.NewIdempotencyRepository(redisClient, cfg.Redis.IdempotencyTTL, cfg.Redis.IdempotencyLease),
+        logger,
+    )
+
+    applicationEvents := redis.NewApplicationEvents(redisClient, cfg.Redis.ApplicationEventsMaxLen)
 
     chatService := service.NewChatService(
         chatRepo,
         sequenceRepo,
-        rabbitMQ,
+        applicationEvents,
         logger,
     )
     
     messageService := service.NewMessageService(
+        messageRepo,
         messageRepo,
         chatRepo,
         sequenceRepo,
-        rabbitMQ,
-        esClient,
+        redis.NewChatStream(redisClient),
+        applicationEvents,
         logger,
     )
 
+    eventService := service.NewEventService(
+        redis.NewApplicationEvents(feedRedisClient, cfg.Redis.ApplicationEventsMaxLen),
+        logger,
+    )
+
+    outboxRelay := service.NewOutboxRelay(
+        outboxRepo,
+        publisher,
+        service.OutboxRelayConfig{
+            PollInterval: cfg.Outbox.PollInterval,
+            BatchSize:    cfg.Outbox.BatchSize,
+            Retention:    cfg.Outbox.Retention,
+        },
+        logger,
+    )
+
+    relayCtx, stopRelay := context.WithCancel(context.Background())
+    relayDone := make(chan struct{})
+    go func() {
+        defer close(relayDone)
+        outboxRelay.Run(relayCtx)
+    }()
+
+    // The counter worker needs RabbitMQ, so on the in-process bus the server
+    // keeps chats_count and messages_count itself.
+    counterCtx, stopCounter := context.WithCancel(context.Background())
+    counterDone := make(chan struct{})
+    if memoryBus != nil {
+        counterConsumer := consumer.NewMemoryCounterConsumer(
+            mysql.NewCounterRepository(db),
+            memoryBus,
+            consumer.CounterConsumerConfig{
+                BatchSize:     cfg.Counter.BatchSize,
+                FlushInterval: cfg.Counter.FlushInterval,
+                Retention:     cfg.Counter.Retention,
+            },
+            logger,
+        )
+        go func() {
+            defer close(counterDone)
+            if err := counterConsumer.Run(counterCtx); err != nil {
+                logger.Error("Counter consumer stopped with error", zap.Error(err))
+            }
+        }()
+    } else {
+        close(counterDone)
+    }
+
     chatHandler := handler.NewChatHandler(chatService, logger)
     messageHandler := handler.NewMessageHandler(messageService, logger)
+    streamHandler := handler.NewStreamHandler(messageService, logger)
+    eventHandler := handler.NewEventHandler(eventService, logger)
 
     router := mux.NewRouter()
+
+    applications := router.PathPrefix("/applications/{token}").Subrouter()
+    applications.Use(handler.RequireApplication(applicationService, logger))
+    
+    idempotent := handler.Idempotent(idempotencyService, logger)
     
-    router.HandleFunc("/applications/{token}/chats", chatHandler.Create).Methods("POST")
-    router.HandleFunc("/applications/{token}/chats/{number}/messages", messageHandler.Create).Methods("POST")
-    router.HandleFunc("/applications/{token}/chats/{number}/messages", messageHandler.List).Methods("GET")
-    router.HandleFunc("/applications/{token}/chats/{number}/messages/search", messageHandler.Search).Methods("GET")
-    router.HandleFunc("/applications/{token}/chats/", chatHandler.ListChats).Methods("GET")
+    applications.Handle("/chats", idempotent(http.HandlerFunc(chatHandler.Create))).Methods("POST")
+    applications.Handle("/chats/{number}/messages", idempotent(http.HandlerFunc(messageHandler.Create))).Methods("POST")
+    applications.Handle("/chats/{number}/messages:batch", idempotent(http.HandlerFunc(messageHandler.CreateBatch))).Methods("POST")
+    applications.HandleFunc("/chats/{number}/messages", messageHandler.List).Methods("GET")
+    applications.HandleFunc("/chats/{number}/messages/search", messageHandler.Search).Methods("GET")
+    applications.HandleFunc("/messages/search", messageHandler.SearchApplication).Methods("GET")
+    applications.HandleFunc("/chats/{number}/messages/{message_number:[0-9]+}", messageHandler.Get).Methods("GET")
+    applications.HandleFunc("/chats/{number}/stream", streamHandler.Stream).Methods("GET")
+    applications.HandleFunc("/events", eventHandler.Events).Methods("GET")
+    applications.HandleFunc("/chats/{number}/messages/{message_number:[0-9]+}", messageHandler.Update).Methods("PATCH")
+    applications.HandleFunc("/chats/{number}/messages/{message_number:[0-9]+}", messageHandler.Delete).Methods("DELETE")
+    applications.HandleFunc("/chats/", chatHandler.ListChats).Methods("GET")
+    applications.HandleFunc("/chats/{number:[0-9]+}", chatHandler.Get).Methods("GET")
+
+    // Admin routes exist only when a token is configured, and the dead
+    // letter ones only with RabbitMQ.
+    if cfg.Admin.Token != "" && rabbitMQ != nil {
+        deadLetterHandler := handler.NewDeadLetterHandler(
+            service.NewDeadLetterService(rabbitMQ, logger),
+            logger,
+        )
+
+        admin := router.PathPrefix("/admin").Subrouter()
+        admin.Use(handler.RequireAdminToken(cfg.Admin.Token))
+        admin.HandleFunc("/queues/{queue}/dead-letters", deadLetterHandler.List).Methods("GET")
+        admin.HandleFunc("/queues/{queue}/dead-letters/replay", deadLetterHandler.Replay).Methods("POST")
+    } else {
+        logger.Info("Admin routes disabled, they need ADMIN_TOKEN and EVENT_BUS=rabbitmq")
+    }
 
     router.PathPrefix("/swagger/").Handler(httpSwagger.Handler(
         httpSwagger.URL("http://localhost:8080/swagger/doc.json"),
@@ -127,6 +292,10 @@ func main() {
         WriteTimeout: 15 * time.Second,
         IdleTimeout:  60 * time.Second,
     }
+    // WebSocket connections are hijacked and not tracked by Shutdown.
+    srv.RegisterOnShutdown(streamHandler.Close)
+    // Event feeds never finish on their own; end them so Shutdown can.
+    srv.RegisterOnShutdown(eventHandler.Close)
 
     go func() {
         logger.Info("Starting server on :8080")
@@ -148,5 +317,15 @@ func main() {
         logger.Fatal("Failed to gracefully shutdown server", zap.Error(err))
     }
 
+    stopRelay()
+    <-relayDone
+
+    stopCounter()
+    <-counterDone
+
+    if err := esIndexer.Close(ctx); err != nil {
+        logger.Error("Failed to flush pending search index updates", zap.Error(err))
+    }
+
     logger.Info("Server stopped")
 }
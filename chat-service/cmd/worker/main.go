@@ -0,0 +1,112 @@
+package main
+
+import (
+    "context"
+    "log"
+    "os"
+    "os/signal"
+    "syscall"
+
+    "go.uber.org/zap"
+
+    "chat-service/config"
+    "chat-service/internal/consumer"
+    "chat-service/internal/repository/mysql"
+    "chat-service/pkg/database"
+    "chat-service/pkg/rabbitmq"
+)
+
+func main() {
+
+    logger, err := zap.NewProduction()
+    if err != nil {
+        log.Fatalf("Failed to create logger: %v", err)
+    }
+    defer logger.Sync()
+
+    cfg, err := config.Load()
+    if err != nil {
+        logger.Fatal("Error loading configuration", zap.Error(err))
+    }
+
+    db, err := database.NewMySQLConnection(database.MySQLConfig{
+        Host:     cfg.MySQL.Host,
+        Port:     cfg.MySQL.Port,
+        User:     cfg.MySQL.User,
+        Password: cfg.MySQL.Password,
+        Database: cfg.MySQL.Database,
+    })
+    if err != nil {
+        logger.Fatal("Failed to connect to MySQL", zap.Error(err))
+    }
+    defer db.Close()
+
+    if cfg.EventBus.Driver != config.EventBusRabbitMQ {
+        logger.Fatal("The counter worker consumes from RabbitMQ and needs EVENT_BUS=rabbitmq",
+            zap.String("EVENT_BUS", cfg.EventBus.Driver))
+    }
+
+    queueBindings, err := rabbitmq.ParseQueueBindings(cfg.RabbitMQ.Queues)
+    if err != nil {
+        logger.Fatal("Invalid RabbitMQ queue configuration", zap.Error(err))
+    }
+
+    rabbitMQ, err := rabbitmq.NewClient(rabbitmq.Config{
+        Host:                cfg.RabbitMQ.Host,
+        Port:                cfg.RabbitMQ.Port,
+        User:                cfg.RabbitMQ.User,
+        Password:            cfg.RabbitMQ.Password,
+        ReconnectMinBackoff: cfg.RabbitMQ.ReconnectMinBackoff,
+        ReconnectMaxBackoff: cfg.RabbitMQ.ReconnectMaxBackoff,
+        PublishTimeout:      cfg.RabbitMQ.PublishTimeout,
+        BufferSize:          cfg.RabbitMQ.BufferSize,
+        Topology: rabbitmq.Topology{
+            Queues:             queueBindings,
+            RetryExchange:      cfg.RabbitMQ.RetryExchange,
+            DeadLetterExchange: cfg.RabbitMQ.DeadLetterExchange,
+            RetryDelay:         cfg.RabbitMQ.RetryDelay,
+            MaxRetries:         cfg.RabbitMQ.MaxRetries,
+        },
+    })
+    if err != nil {
+        logger.Fatal("Failed to connect to RabbitMQ", zap.Error(err))
+    }
+    defer rabbitMQ.Close()
+
+    counterConsumer := consumer.NewCounterConsumer(
+        mysql.NewCounterRepository(db),
+        rabbitMQ,
+        consumer.CounterConsumerConfig{
+            BatchSize:     cfg.Counter.BatchSize,
+            FlushInterval: cfg.Counter.FlushInterval,
+            Retention:     cfg.Counter.Retention,
+        },
+        logger,
+    )
+
+    ctx, cancel := context.WithCancel(context.Background())
+    done := make(chan error, 1)
+    go func() {
+        logger.Info("Starting counter worker")
+        done <- counterConsumer.Run(ctx)
+    }()
+
+    stop := make(chan os.Signal, 1)
+    signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+
+    select {
+    case <-stop:
+        logger.Info("Shutting down gracefully...")
+        cancel()
+        if err := <-done; err != nil {
+            logger.Error("Counter worker stopped with error", zap.Error(err))
+        }
+    case err := <-done:
+        cancel()
+        if err != nil {
+            logger.Fatal("Counter worker failed", zap.Error(err))
+        }
+    }
+
+    logger.Info("Worker stopped")
+}
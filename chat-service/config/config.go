@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/spf13/viper"
 )
 
@@ -9,8 +11,18 @@ type Config struct {
 	Redis         RedisConfig
 	RabbitMQ      RabbitMQConfig
 	Elasticsearch ElasticsearchConfig
+	Outbox        OutboxConfig
+	Counter       CounterConfig
+	Admin         AdminConfig
+	EventBus      EventBusConfig
 }
 
+// Event bus drivers selectable with EVENT_BUS.
+const (
+	EventBusRabbitMQ = "rabbitmq"
+	EventBusMemory   = "memory"
+)
+
 type MySQLConfig struct {
 	Host     string
 	Port     string
@@ -20,25 +32,94 @@ type MySQLConfig struct {
 }
 
 type RedisConfig struct {
-	Host string
-	Port string
+	Host                    string
+	Port                    string
+	ApplicationCacheTTL     time.Duration
+	IdempotencyTTL          time.Duration
+	IdempotencyLease        time.Duration
+	ApplicationEventsMaxLen int64
+	FeedPoolSize            int
 }
 
 type RabbitMQConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
+	Host                string
+	Port                string
+	User                string
+	Password            string
+	ReconnectMinBackoff time.Duration
+	ReconnectMaxBackoff time.Duration
+	PublishTimeout      time.Duration
+	BufferSize          int
+	Queues              string
+	RetryExchange       string
+	DeadLetterExchange  string
+	RetryDelay          time.Duration
+	MaxRetries          int
 }
 
 type ElasticsearchConfig struct {
-	URL string
+	URL              string
+	BulkSize         int
+	BulkFlush        time.Duration
+	BulkQueueSize    int
+	BulkMaxRetries   int
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+type OutboxConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	Retention    time.Duration
+}
+
+type CounterConfig struct {
+	BatchSize     int
+	FlushInterval time.Duration
+	Retention     time.Duration
+}
+
+type AdminConfig struct {
+	Token string
+}
+
+type EventBusConfig struct {
+	Driver       string
+	MemoryBuffer int
 }
 
 func Load() (*Config, error) {
 	viper.SetConfigFile(".env")
 
 	viper.AutomaticEnv()
+	viper.SetDefault("APPLICATION_CACHE_TTL", "24h")
+	viper.SetDefault("IDEMPOTENCY_TTL", "24h")
+	viper.SetDefault("IDEMPOTENCY_LEASE", "60s")
+	viper.SetDefault("APPLICATION_EVENTS_MAX_LEN", 1000)
+	viper.SetDefault("REDIS_FEED_POOL_SIZE", 100)
+	viper.SetDefault("RABBITMQ_RECONNECT_MIN_BACKOFF", "1s")
+	viper.SetDefault("RABBITMQ_RECONNECT_MAX_BACKOFF", "30s")
+	viper.SetDefault("RABBITMQ_PUBLISH_TIMEOUT", "5s")
+	viper.SetDefault("RABBITMQ_BUFFER_SIZE", 1000)
+	viper.SetDefault("RABBITMQ_QUEUES", "counters.chat_created:chat_created,counters.message_created:message_created,counters.message_deleted:message_deleted")
+	viper.SetDefault("RABBITMQ_RETRY_EXCHANGE", "chat.retry")
+	viper.SetDefault("RABBITMQ_DEAD_LETTER_EXCHANGE", "chat.dlx")
+	viper.SetDefault("RABBITMQ_RETRY_DELAY", "30s")
+	viper.SetDefault("RABBITMQ_MAX_RETRIES", 5)
+	viper.SetDefault("EVENT_BUS", EventBusRabbitMQ)
+	viper.SetDefault("EVENT_BUS_MEMORY_BUFFER", 1000)
+	viper.SetDefault("OUTBOX_POLL_INTERVAL", "1s")
+	viper.SetDefault("OUTBOX_BATCH_SIZE", 100)
+	viper.SetDefault("OUTBOX_RETENTION", "24h")
+	viper.SetDefault("COUNTER_BATCH_SIZE", 500)
+	viper.SetDefault("COUNTER_FLUSH_INTERVAL", "1s")
+	viper.SetDefault("COUNTER_DEDUP_RETENTION", "168h")
+	viper.SetDefault("ELASTICSEARCH_BULK_SIZE", 500)
+	viper.SetDefault("ELASTICSEARCH_BULK_FLUSH_INTERVAL", "1s")
+	viper.SetDefault("ELASTICSEARCH_BULK_QUEUE_SIZE", 10000)
+	viper.SetDefault("ELASTICSEARCH_BULK_MAX_RETRIES", 3)
+	viper.SetDefault("ELASTICSEARCH_BREAKER_THRESHOLD", 5)
+	viper.SetDefault("ELASTICSEARCH_BREAKER_COOLDOWN", "30s")
 	if err := viper.ReadInConfig(); err != nil {
 		return nil, err
 	}
@@ -52,17 +133,54 @@ func Load() (*Config, error) {
 			Database: viper.GetString("MYSQL_DATABASE"),
 		},
 		Redis: RedisConfig{
-			Host: viper.GetString("REDIS_HOST"),
-			Port: viper.GetString("REDIS_PORT"),
+			Host:                    viper.GetString("REDIS_HOST"),
+			Port:                    viper.GetString("REDIS_PORT"),
+			ApplicationCacheTTL:     viper.GetDuration("APPLICATION_CACHE_TTL"),
+			IdempotencyTTL:          viper.GetDuration("IDEMPOTENCY_TTL"),
+			IdempotencyLease:        viper.GetDuration("IDEMPOTENCY_LEASE"),
+			ApplicationEventsMaxLen: viper.GetInt64("APPLICATION_EVENTS_MAX_LEN"),
+			FeedPoolSize:            viper.GetInt("REDIS_FEED_POOL_SIZE"),
 		},
 		RabbitMQ: RabbitMQConfig{
-			Host:     viper.GetString("RABBITMQ_HOST"),
-			Port:     viper.GetString("RABBITMQ_PORT"),
-			User:     viper.GetString("RABBITMQ_USER"),
-			Password: viper.GetString("RABBITMQ_PASSWORD"),
+			Host:                viper.GetString("RABBITMQ_HOST"),
+			Port:                viper.GetString("RABBITMQ_PORT"),
+			User:                viper.GetString("RABBITMQ_USER"),
+			Password:            viper.GetString("RABBITMQ_PASSWORD"),
+			ReconnectMinBackoff: viper.GetDuration("RABBITMQ_RECONNECT_MIN_BACKOFF"),
+			ReconnectMaxBackoff: viper.GetDuration("RABBITMQ_RECONNECT_MAX_BACKOFF"),
+			PublishTimeout:      viper.GetDuration("RABBITMQ_PUBLISH_TIMEOUT"),
+			BufferSize:          viper.GetInt("RABBITMQ_BUFFER_SIZE"),
+			Queues:              viper.GetString("RABBITMQ_QUEUES"),
+			RetryExchange:       viper.GetString("RABBITMQ_RETRY_EXCHANGE"),
+			DeadLetterExchange:  viper.GetString("RABBITMQ_DEAD_LETTER_EXCHANGE"),
+			RetryDelay:          viper.GetDuration("RABBITMQ_RETRY_DELAY"),
+			MaxRetries:          viper.GetInt("RABBITMQ_MAX_RETRIES"),
 		},
 		Elasticsearch: ElasticsearchConfig{
-			URL: viper.GetString("ELASTICSEARCH_URL"),
+			URL:              viper.GetString("ELASTICSEARCH_URL"),
+			BulkSize:         viper.GetInt("ELASTICSEARCH_BULK_SIZE"),
+			BulkFlush:        viper.GetDuration("ELASTICSEARCH_BULK_FLUSH_INTERVAL"),
+			BulkQueueSize:    viper.GetInt("ELASTICSEARCH_BULK_QUEUE_SIZE"),
+			BulkMaxRetries:   viper.GetInt("ELASTICSEARCH_BULK_MAX_RETRIES"),
+			BreakerThreshold: viper.GetInt("ELASTICSEARCH_BREAKER_THRESHOLD"),
+			BreakerCooldown:  viper.GetDuration("ELASTICSEARCH_BREAKER_COOLDOWN"),
+		},
+		Outbox: OutboxConfig{
+			PollInterval: viper.GetDuration("OUTBOX_POLL_INTERVAL"),
+			BatchSize:    viper.GetInt("OUTBOX_BATCH_SIZE"),
+			Retention:    viper.GetDuration("OUTBOX_RETENTION"),
+		},
+		Counter: CounterConfig{
+			BatchSize:     viper.GetInt("COUNTER_BATCH_SIZE"),
+			FlushInterval: viper.GetDuration("COUNTER_FLUSH_INTERVAL"),
+			Retention:     viper.GetDuration("COUNTER_DEDUP_RETENTION"),
+		},
+		Admin: AdminConfig{
+			Token: viper.GetString("ADMIN_TOKEN"),
+		},
+		EventBus: EventBusConfig{
+			Driver:       viper.GetString("EVENT_BUS"),
+			MemoryBuffer: viper.GetInt("EVENT_BUS_MEMORY_BUFFER"),
 		},
 	}, nil
 }
@@ -0,0 +1,254 @@
+package consumer
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "time"
+
+    "github.com/streadway/amqp"
+    "go.uber.org/zap"
+
+    "chat-service/internal/model"
+    "chat-service/internal/repository/mysql"
+    "chat-service/pkg/events"
+    "chat-service/pkg/rabbitmq"
+)
+
+const (
+    chatCounterQueue    = "counters.chat_created"
+    messageCounterQueue = "counters.message_created"
+    messageDeletedQueue = "counters.message_deleted"
+)
+
+// counterQueues maps each consumed event type to its work queue, which has
+// to be part of the RabbitMQ topology.
+var counterQueues = map[string]string{
+    model.EventChatCreated:    chatCounterQueue,
+    model.EventMessageCreated: messageCounterQueue,
+    model.EventMessageDeleted: messageDeletedQueue,
+}
+
+type CounterConsumerConfig struct {
+    BatchSize     int
+    FlushInterval time.Duration
+    Retention     time.Duration
+}
+
+// CounterConsumer maintains applications.chats_count and chats.messages_count
+// from chat_created, message_created and message_deleted events. Increments are buffered and
+// written in one transaction per batch; deliveries are acked only after that
+// transaction commits, so a crash at any point leads to redelivery rather
+// than lost counts. A batch that fails is retried after a delay, and
+// malformed events go straight to the dead-letter queue.
+type CounterConsumer struct {
+    counterRepo *mysql.CounterRepository
+    rabbitMQ    *rabbitmq.Client
+    cfg         CounterConsumerConfig
+    logger      *zap.Logger
+
+    events     []model.CounterEvent
+    deliveries []amqp.Delivery
+    queues     []string
+}
+
+func NewCounterConsumer(
+    counterRepo *mysql.CounterRepository,
+    rabbitMQ *rabbitmq.Client,
+    cfg CounterConsumerConfig,
+    logger *zap.Logger,
+) *CounterConsumer {
+    return &CounterConsumer{
+        counterRepo: counterRepo,
+        rabbitMQ:    rabbitMQ,
+        cfg:         cfg.withDefaults(),
+        logger:      logger,
+    }
+}
+
+func (cfg CounterConsumerConfig) withDefaults() CounterConsumerConfig {
+    if cfg.BatchSize <= 0 {
+        cfg.BatchSize = 500
+    }
+    if cfg.FlushInterval <= 0 {
+        cfg.FlushInterval = time.Second
+    }
+    if cfg.Retention <= 0 {
+        cfg.Retention = 7 * 24 * time.Hour
+    }
+    return cfg
+}
+
+// Run consumes until ctx is cancelled or the broker closes the deliveries
+// channels. FlushInterval bounds how long an increment waits in memory.
+func (c *CounterConsumer) Run(ctx context.Context) error {
+    prefetch := c.cfg.BatchSize * 2
+
+    chats, err := c.rabbitMQ.Consume(chatCounterQueue, prefetch)
+    if err != nil {
+        return err
+    }
+    messages, err := c.rabbitMQ.Consume(messageCounterQueue, prefetch)
+    if err != nil {
+        return err
+    }
+    deletions, err := c.rabbitMQ.Consume(messageDeletedQueue, prefetch)
+    if err != nil {
+        return err
+    }
+
+    ticker := time.NewTicker(c.cfg.FlushInterval)
+    defer ticker.Stop()
+
+    cleanup := time.NewTicker(time.Hour)
+    defer cleanup.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            c.flush(context.Background())
+            return nil
+        case d, ok := <-chats:
+            if !ok {
+                c.flush(ctx)
+                return fmt.Errorf("chat_created deliveries channel closed")
+            }
+            c.add(ctx, model.EventChatCreated, d)
+        case d, ok := <-messages:
+            if !ok {
+                c.flush(ctx)
+                return fmt.Errorf("message_created deliveries channel closed")
+            }
+            c.add(ctx, model.EventMessageCreated, d)
+        case d, ok := <-deletions:
+            if !ok {
+                c.flush(ctx)
+                return fmt.Errorf("message_deleted deliveries channel closed")
+            }
+            c.add(ctx, model.EventMessageDeleted, d)
+        case <-ticker.C:
+            c.flush(ctx)
+        case <-cleanup.C:
+            pruneProcessedEvents(ctx, c.counterRepo, c.cfg.Retention, c.logger)
+        }
+
+        if len(c.deliveries) >= c.cfg.BatchSize {
+            c.flush(ctx)
+        }
+    }
+}
+
+func (c *CounterConsumer) add(ctx context.Context, eventType string, d amqp.Delivery) {
+    queue := counterQueues[eventType]
+
+    event, err := counterEventFromDelivery(eventType, d.Body)
+    if err != nil {
+        c.logger.Error("dead-lettering malformed event",
+            zap.Error(err),
+            zap.String("event_type", eventType))
+        if err := c.rabbitMQ.DeadLetter(context.WithoutCancel(ctx), d, queue, err); err != nil {
+            c.logger.Error("failed to dead-letter delivery", zap.Error(err))
+        }
+        return
+    }
+
+    c.events = append(c.events, event)
+    c.deliveries = append(c.deliveries, d)
+    c.queues = append(c.queues, queue)
+}
+
+func (c *CounterConsumer) flush(ctx context.Context) {
+    if len(c.deliveries) == 0 {
+        return
+    }
+
+    applied, err := c.counterRepo.ApplyIncrements(ctx, c.events)
+    if err != nil {
+        c.logger.Error("failed to apply counter increments, retrying batch later",
+            zap.Error(err),
+            zap.Int("batch_size", len(c.deliveries)))
+        for i, d := range c.deliveries {
+            if err := c.rabbitMQ.Retry(context.WithoutCancel(ctx), d, c.queues[i], err); err != nil {
+                c.logger.Error("failed to retry delivery", zap.Error(err))
+            }
+        }
+    } else {
+        for _, d := range c.deliveries {
+            if err := d.Ack(false); err != nil {
+                c.logger.Error("failed to ack delivery", zap.Error(err))
+            }
+        }
+        c.logger.Debug("applied counter increments",
+            zap.Int("batch_size", len(c.deliveries)),
+            zap.Int("applied", applied))
+    }
+
+    c.events = c.events[:0]
+    c.deliveries = c.deliveries[:0]
+    c.queues = c.queues[:0]
+}
+
+// pruneProcessedEvents forgets the keys of events processed longer than
+// retention ago.
+func pruneProcessedEvents(ctx context.Context, counterRepo *mysql.CounterRepository, retention time.Duration, logger *zap.Logger) {
+    deleted, err := counterRepo.DeleteProcessedBefore(ctx, time.Now().UTC().Add(-retention))
+    if err != nil {
+        logger.Error("failed to prune processed events", zap.Error(err))
+    } else if deleted > 0 {
+        logger.Info("pruned processed events", zap.Int64("deleted", deleted))
+    }
+}
+
+func counterEventFromDelivery(eventType string, body []byte) (model.CounterEvent, error) {
+    // Events published before envelopes were introduced carry the bare
+    // payload.
+    envelope, err := events.Parse(body)
+    switch {
+    case err == nil:
+        body = envelope.Data
+    case !errors.Is(err, events.ErrNotEnvelope):
+        return model.CounterEvent{}, err
+    }
+
+    return counterEventFromData(eventType, body)
+}
+
+// counterEventFromData derives the counter change of an event from its data
+// payload.
+func counterEventFromData(eventType string, body []byte) (model.CounterEvent, error) {
+    switch eventType {
+    case model.EventChatCreated:
+        var chat events.Chat
+        if err := json.Unmarshal(body, &chat); err != nil {
+            return model.CounterEvent{}, fmt.Errorf("failed to unmarshal chat: %w", err)
+        }
+        if chat.ID == 0 || chat.ApplicationID == "" {
+            return model.CounterEvent{}, fmt.Errorf("chat event is missing id or application")
+        }
+        return model.CounterEvent{
+            Key:              fmt.Sprintf("%s:%d", eventType, chat.ID),
+            ApplicationToken: chat.ApplicationID,
+            Delta:            1,
+        }, nil
+    case model.EventMessageCreated, model.EventMessageDeleted:
+        var message events.Message
+        if err := json.Unmarshal(body, &message); err != nil {
+            return model.CounterEvent{}, fmt.Errorf("failed to unmarshal message: %w", err)
+        }
+        if message.ID == 0 || message.ChatID == 0 {
+            return model.CounterEvent{}, fmt.Errorf("message event is missing id or chat")
+        }
+        delta := 1
+        if eventType == model.EventMessageDeleted {
+            delta = -1
+        }
+        return model.CounterEvent{
+            Key:    fmt.Sprintf("%s:%d", eventType, message.ID),
+            ChatID: message.ChatID,
+            Delta:  delta,
+        }, nil
+    default:
+        return model.CounterEvent{}, fmt.Errorf("unknown event type %q", eventType)
+    }
+}
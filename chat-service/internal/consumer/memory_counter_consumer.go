@@ -0,0 +1,129 @@
+package consumer
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    "go.uber.org/zap"
+
+    "chat-service/internal/model"
+    "chat-service/internal/repository/mysql"
+    "chat-service/pkg/events"
+)
+
+// MemoryCounterConsumer maintains the same counters as CounterConsumer from
+// the in-process event bus, for a server running without RabbitMQ. The bus
+// does not redeliver, so a batch that fails to apply is kept and retried at
+// the next flush interval; ApplyIncrements skips the events already applied.
+// Malformed events are logged and dropped.
+type MemoryCounterConsumer struct {
+    counterRepo  *mysql.CounterRepository
+    subscription *events.Subscription
+    cfg          CounterConsumerConfig
+    logger       *zap.Logger
+
+    events   []model.CounterEvent
+    retrying bool
+}
+
+// NewMemoryCounterConsumer subscribes to bus right away, so no event
+// published before Run starts is missed.
+func NewMemoryCounterConsumer(
+    counterRepo *mysql.CounterRepository,
+    bus *events.MemoryBus,
+    cfg CounterConsumerConfig,
+    logger *zap.Logger,
+) *MemoryCounterConsumer {
+    return &MemoryCounterConsumer{
+        counterRepo:  counterRepo,
+        subscription: bus.Subscribe(model.EventChatCreated, model.EventMessageCreated, model.EventMessageDeleted),
+        cfg:          cfg.withDefaults(),
+        logger:       logger,
+    }
+}
+
+// Run consumes until ctx is cancelled, then applies the events already on
+// the bus before returning.
+func (c *MemoryCounterConsumer) Run(ctx context.Context) error {
+    defer c.subscription.Close()
+
+    ticker := time.NewTicker(c.cfg.FlushInterval)
+    defer ticker.Stop()
+
+    cleanup := time.NewTicker(time.Hour)
+    defer cleanup.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            c.drain()
+            c.flush(context.Background())
+            return nil
+        case envelope, ok := <-c.subscription.Events():
+            if !ok {
+                c.flush(ctx)
+                return fmt.Errorf("memory bus subscription closed")
+            }
+            c.add(envelope)
+        case <-ticker.C:
+            c.flush(ctx)
+        case <-cleanup.C:
+            pruneProcessedEvents(ctx, c.counterRepo, c.cfg.Retention, c.logger)
+        }
+
+        // While a batch keeps failing, only the ticker retries it.
+        if len(c.events) >= c.cfg.BatchSize && !c.retrying {
+            c.flush(ctx)
+        }
+    }
+}
+
+func (c *MemoryCounterConsumer) add(envelope *events.Envelope) {
+    event, err := counterEventFromData(envelope.Type, envelope.Data)
+    if err != nil {
+        c.logger.Error("dropping malformed event",
+            zap.Error(err),
+            zap.String("event_id", envelope.ID),
+            zap.String("event_type", envelope.Type))
+        return
+    }
+
+    c.events = append(c.events, event)
+}
+
+// drain takes the events still buffered in the subscription.
+func (c *MemoryCounterConsumer) drain() {
+    for {
+        select {
+        case envelope, ok := <-c.subscription.Events():
+            if !ok {
+                return
+            }
+            c.add(envelope)
+        default:
+            return
+        }
+    }
+}
+
+func (c *MemoryCounterConsumer) flush(ctx context.Context) {
+    if len(c.events) == 0 {
+        return
+    }
+
+    applied, err := c.counterRepo.ApplyIncrements(ctx, c.events)
+    if err != nil {
+        c.logger.Error("failed to apply counter increments, retrying batch later",
+            zap.Error(err),
+            zap.Int("batch_size", len(c.events)))
+        c.retrying = true
+        return
+    }
+
+    c.logger.Debug("applied counter increments",
+        zap.Int("batch_size", len(c.events)),
+        zap.Int("applied", applied))
+    c.events = c.events[:0]
+    c.retrying = false
+}
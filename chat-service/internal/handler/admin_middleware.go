@@ -0,0 +1,27 @@
+package handler
+
+import (
+    "crypto/subtle"
+    "net/http"
+    "strings"
+
+    "github.com/gorilla/mux"
+
+    "chat-service/internal/util"
+)
+
+// RequireAdminToken rejects requests that do not carry token as a bearer
+// token in the Authorization header.
+func RequireAdminToken(token string) mux.MiddlewareFunc {
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+            if !ok || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+                util.RespondWithError(w, http.StatusUnauthorized, "unauthorized", "a valid admin token is required")
+                return
+            }
+
+            next.ServeHTTP(w, r)
+        })
+    }
+}
@@ -0,0 +1,31 @@
+package handler
+
+import (
+    "net/http"
+
+    "github.com/gorilla/mux"
+    "go.uber.org/zap"
+
+    "chat-service/internal/service"
+)
+
+// RequireApplication rejects requests whose {token} path variable does not
+// name an existing application before any handler touches chats, messages
+// or sequence counters.
+func RequireApplication(applications *service.ApplicationService, logger *zap.Logger) mux.MiddlewareFunc {
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            applicationToken := mux.Vars(r)["token"]
+
+            if err := applications.EnsureExists(r.Context(), applicationToken); err != nil {
+                logger.Info("rejected request for application",
+                    zap.Error(err),
+                    zap.String("application_token", applicationToken))
+                respondWithServiceError(w, err, "Failed to look up application")
+                return
+            }
+
+            next.ServeHTTP(w, r)
+        })
+    }
+}
@@ -2,10 +2,10 @@ package handler
 
 import (
     "net/http"
-    "encoding/json"
     "go.uber.org/zap"
     "github.com/gorilla/mux"
     "chat-service/internal/service"
+    "chat-service/internal/util"
 )
 
 type ChatHandler struct {
@@ -26,8 +26,11 @@ func NewChatHandler(service *service.ChatService, logger *zap.Logger) *ChatHandl
 // @Accept      json
 // @Produce     json
 // @Param       token path string true "Application Token"
+// @Param       Idempotency-Key header string false "Key that makes retries return the original response"
 // @Success     201 {object} model.CreateChatResponse
+// @Failure     409 {object} model.ErrorResponse
 // @Failure     500 {object} model.ErrorResponse
+// @Failure     503 {object} model.ErrorResponse
 // @Router      /applications/{token}/chats [post]
 func (h *ChatHandler) Create(w http.ResponseWriter, r *http.Request) {
     vars := mux.Vars(r)
@@ -41,64 +44,93 @@ func (h *ChatHandler) Create(w http.ResponseWriter, r *http.Request) {
         h.logger.Error("failed to create chat",
             zap.Error(err),
             zap.String("application_token", applicationToken))
-        respondWithError(w, http.StatusInternalServerError, "Failed to create chat")
+        respondWithServiceError(w, err, "Failed to create chat")
         return
     }
 
-    respondWithJSON(w, http.StatusCreated, map[string]interface{}{
+    util.RespondWithJSON(w, http.StatusCreated, map[string]interface{}{
         "chat_number": chat.Number,
     })
 }
 
-// @Summary     List all chats
-// @Description Gets all chats for an application
+// @Summary     Get a chat
+// @Description Gets a single chat of an application by its number
+// @Tags        chats
+// @Produce     json
+// @Param       token  path string true "Application Token"
+// @Param       number path int    true "Chat Number"
+// @Success     200 {object} model.ChatResponse
+// @Failure     404 {object} model.ErrorResponse
+// @Failure     500 {object} model.ErrorResponse
+// @Router      /applications/{token}/chats/{number} [get]
+func (h *ChatHandler) Get(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    applicationToken := vars["token"]
+    chatNumber := vars["number"]
+
+    chat, err := h.service.GetChat(r.Context(), applicationToken, chatNumber)
+    if err != nil {
+        h.logger.Error("failed to get chat",
+            zap.Error(err),
+            zap.String("application_token", applicationToken),
+            zap.String("chat_number", chatNumber))
+        respondWithServiceError(w, err, "Failed to get chat")
+        return
+    }
+
+    util.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+        "number":         chat.Number,
+        "messages_count": chat.MessagesCount,
+        "created_at":     chat.CreatedAt,
+    })
+}
+
+// @Summary     List chats
+// @Description Gets one page of chats for an application using keyset pagination
 // @Tags        chats
 // @Accept      json
 // @Produce     json
-// @Param       token path string true "Application Token"
-// @Success     200 {array} model.ChatResponse
+// @Param       token  path  string true  "Application Token"
+// @Param       limit  query int    false "Page size (1-200, default 50)"
+// @Param       after  query string false "Cursor returned as next_cursor"
+// @Param       before query string false "Cursor returned as prev_cursor"
+// @Param       order  query string false "asc (oldest first, default) or desc (latest first)"
+// @Success     200 {object} model.ChatListResponse
 // @Failure     400 {object} model.ErrorResponse
 // @Failure     404 {object} model.ErrorResponse
+// @Failure     500 {object} model.ErrorResponse
 // @Router      /applications/{token}/chats [get]
 func (h *ChatHandler) ListChats(w http.ResponseWriter, r *http.Request) {
     vars := mux.Vars(r)
     applicationToken := vars["token"]
 
-    chats, err := h.service.ListChats(r.Context(), applicationToken)
+    page, err := parsePageQuery(r)
+    if err != nil {
+        respondWithServiceError(w, err, "Invalid pagination parameters")
+        return
+    }
+
+    result, err := h.service.ListChats(r.Context(), applicationToken, page)
     if err != nil {
         h.logger.Error("failed to list chats",
             zap.Error(err),
             zap.String("application_token", applicationToken))
-        respondWithError(w, http.StatusInternalServerError, err.Error())
+        respondWithServiceError(w, err, "Failed to list chats")
         return
     }
 
-    response := make([]map[string]interface{}, len(chats))
-    for i, chat := range chats {
-        response[i] = map[string]interface{}{
+    chats := make([]map[string]interface{}, len(result.Chats))
+    for i, chat := range result.Chats {
+        chats[i] = map[string]interface{}{
             "number":         chat.Number,
             "messages_count": chat.MessagesCount,
             "created_at":     chat.CreatedAt,
         }
     }
 
-    respondWithJSON(w, http.StatusOK, response)
-}
-
-// Helper functions for response handling
-func respondWithError(w http.ResponseWriter, code int, message string) {
-    respondWithJSON(w, code, map[string]string{"error": message})
+    util.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+        "chats":       chats,
+        "next_cursor": result.NextCursor,
+        "prev_cursor": result.PrevCursor,
+    })
 }
-
-func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
-    response, err := json.Marshal(payload)
-    if err != nil {
-        w.WriteHeader(http.StatusInternalServerError)
-        w.Write([]byte("Internal Server Error"))
-        return
-    }
-
-    w.Header().Set("Content-Type", "application/json")
-    w.WriteHeader(code)
-    w.Write(response)
-}
\ No newline at end of file
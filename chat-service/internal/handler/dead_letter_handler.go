@@ -0,0 +1,125 @@
+package handler
+
+import (
+    "fmt"
+    "net/http"
+    "strconv"
+
+    "github.com/gorilla/mux"
+    "go.uber.org/zap"
+
+    "chat-service/internal/model"
+    "chat-service/internal/service"
+    "chat-service/internal/util"
+)
+
+type DeadLetterHandler struct {
+    service *service.DeadLetterService
+    logger  *zap.Logger
+}
+
+func NewDeadLetterHandler(service *service.DeadLetterService, logger *zap.Logger) *DeadLetterHandler {
+    return &DeadLetterHandler{
+        service: service,
+        logger:  logger,
+    }
+}
+
+// @Summary     List dead letters
+// @Description Returns the oldest messages of a work queue's dead-letter queue without removing them
+// @Tags        admin
+// @Produce     json
+// @Security    AdminToken
+// @Param       queue path  string true  "Work queue, e.g. counters.message_created"
+// @Param       limit query int    false "Number of messages (1-500, default 20)"
+// @Success     200 {object} model.DeadLetterListResponse
+// @Failure     400 {object} model.ErrorResponse
+// @Failure     401 {object} model.ErrorResponse
+// @Failure     404 {object} model.ErrorResponse
+// @Failure     503 {object} model.ErrorResponse
+// @Router      /admin/queues/{queue}/dead-letters [get]
+func (h *DeadLetterHandler) List(w http.ResponseWriter, r *http.Request) {
+    queue := mux.Vars(r)["queue"]
+
+    limit, err := parseDeadLetterLimit(r)
+    if err != nil {
+        respondWithServiceError(w, err, "")
+        return
+    }
+
+    letters, err := h.service.List(r.Context(), queue, limit)
+    if err != nil {
+        h.logger.Error("failed to list dead letters",
+            zap.Error(err),
+            zap.String("queue", queue))
+        respondWithServiceError(w, err, "Failed to list dead letters")
+        return
+    }
+
+    response := model.DeadLetterListResponse{
+        Queue:       queue,
+        DeadLetters: make([]model.DeadLetterResponse, len(letters)),
+    }
+    for i, letter := range letters {
+        response.DeadLetters[i] = model.DeadLetterResponse{
+            Exchange:       letter.Exchange,
+            Body:           letter.Body,
+            Error:          letter.Error,
+            Retries:        letter.Retries,
+            DeadLetteredAt: letter.DeadLetteredAt,
+        }
+    }
+
+    util.RespondWithJSON(w, http.StatusOK, response)
+}
+
+// @Summary     Replay dead letters
+// @Description Moves the oldest messages of a work queue's dead-letter queue back to the work queue with a fresh retry count
+// @Tags        admin
+// @Produce     json
+// @Security    AdminToken
+// @Param       queue path  string true  "Work queue, e.g. counters.message_created"
+// @Param       limit query int    false "Number of messages (1-500, default 20)"
+// @Success     200 {object} model.ReplayDeadLettersResponse
+// @Failure     400 {object} model.ErrorResponse
+// @Failure     401 {object} model.ErrorResponse
+// @Failure     404 {object} model.ErrorResponse
+// @Failure     503 {object} model.ErrorResponse
+// @Router      /admin/queues/{queue}/dead-letters/replay [post]
+func (h *DeadLetterHandler) Replay(w http.ResponseWriter, r *http.Request) {
+    queue := mux.Vars(r)["queue"]
+
+    limit, err := parseDeadLetterLimit(r)
+    if err != nil {
+        respondWithServiceError(w, err, "")
+        return
+    }
+
+    replayed, err := h.service.Replay(r.Context(), queue, limit)
+    if err != nil {
+        h.logger.Error("failed to replay dead letters",
+            zap.Error(err),
+            zap.String("queue", queue),
+            zap.Int("replayed", replayed))
+        respondWithServiceError(w, err, fmt.Sprintf("Failed to replay dead letters, %d replayed", replayed))
+        return
+    }
+
+    util.RespondWithJSON(w, http.StatusOK, model.ReplayDeadLettersResponse{
+        Queue:    queue,
+        Replayed: replayed,
+    })
+}
+
+func parseDeadLetterLimit(r *http.Request) (int, error) {
+    raw := r.URL.Query().Get("limit")
+    if raw == "" {
+        return model.DefaultDeadLetterLimit, nil
+    }
+
+    limit, err := strconv.Atoi(raw)
+    if err != nil || limit < 1 || limit > model.MaxDeadLetterLimit {
+        return 0, service.InvalidArgument(fmt.Sprintf("limit must be between 1 and %d", model.MaxDeadLetterLimit))
+    }
+    return limit, nil
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+    "errors"
+    "net/http"
+
+    "chat-service/internal/service"
+    "chat-service/internal/util"
+)
+
+var statusByKind = map[service.ErrorKind]int{
+    service.KindInvalidArgument: http.StatusBadRequest,
+    service.KindNotFound:        http.StatusNotFound,
+    service.KindConflict:        http.StatusConflict,
+    service.KindUnavailable:     http.StatusServiceUnavailable,
+    service.KindInternal:        http.StatusInternalServerError,
+}
+
+// respondWithServiceError is the single translation from service errors to
+// HTTP responses. Client-facing kinds expose the error's own message; for
+// internal and unavailable failures the generic fallback is shown instead so
+// storage details never leak.
+func respondWithServiceError(w http.ResponseWriter, err error, fallback string) {
+    if recorder, ok := w.(*responseRecorder); ok {
+        recorder.err = err
+    }
+
+    var serviceErr *service.Error
+    if !errors.As(err, &serviceErr) {
+        util.RespondWithError(w, http.StatusInternalServerError, string(service.KindInternal), fallback)
+        return
+    }
+
+    status, ok := statusByKind[serviceErr.Kind]
+    if !ok {
+        status = http.StatusInternalServerError
+    }
+
+    message := serviceErr.Message
+    if status >= http.StatusInternalServerError {
+        message = fallback
+    }
+
+    util.RespondWithError(w, status, serviceErr.Code, message)
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "time"
+
+    "github.com/gorilla/mux"
+    "go.uber.org/zap"
+
+    "chat-service/internal/model"
+    "chat-service/internal/service"
+)
+
+// eventsRetry is the reconnect delay suggested to EventSource clients.
+const eventsRetry = 3 * time.Second
+
+// EventHandler serves the server-sent events feed of an application.
+// Shutdown waits for in-flight requests, so Close ends open feeds first.
+type EventHandler struct {
+    service *service.EventService
+    logger  *zap.Logger
+    ctx     context.Context
+    cancel  context.CancelFunc
+}
+
+func NewEventHandler(service *service.EventService, logger *zap.Logger) *EventHandler {
+    ctx, cancel := context.WithCancel(context.Background())
+    return &EventHandler{
+        service: service,
+        logger:  logger,
+        ctx:     ctx,
+        cancel:  cancel,
+    }
+}
+
+// Close ends every open feed.
+func (h *EventHandler) Close() {
+    h.cancel()
+}
+
+// @Summary     Stream application events
+// @Description Server-sent events feed of chat_created and message_created events of the application. The data of each event is the chat or message as published to RabbitMQ. Reconnect with Last-Event-ID to resume after the last event received; a resync event means some events were trimmed and the client should reload its state.
+// @Tags        applications
+// @Produce     text/event-stream
+// @Param       token         path   string true  "Application Token"
+// @Param       Last-Event-ID header string false "Resume after this event id"
+// @Success     200
+// @Failure     400 {object} model.ErrorResponse
+// @Failure     404 {object} model.ErrorResponse
+// @Failure     503 {object} model.ErrorResponse
+// @Router      /applications/{token}/events [get]
+func (h *EventHandler) Events(w http.ResponseWriter, r *http.Request) {
+    applicationToken := mux.Vars(r)["token"]
+
+    lastEventID := r.Header.Get("Last-Event-ID")
+
+    feed, err := h.service.OpenFeed(r.Context(), applicationToken, lastEventID)
+    if err != nil {
+        respondWithServiceError(w, err, "Failed to open application events")
+        return
+    }
+
+    // The server's WriteTimeout would otherwise cut the feed off.
+    controller := http.NewResponseController(w)
+    if err := controller.SetWriteDeadline(time.Time{}); err != nil {
+        h.logger.Warn("failed to clear write deadline of event feed", zap.Error(err))
+    }
+
+    w.Header().Set("Content-Type", "text/event-stream")
+    w.Header().Set("Cache-Control", "no-cache")
+    w.Header().Set("Connection", "keep-alive")
+    w.Header().Set("X-Accel-Buffering", "no")
+    w.WriteHeader(http.StatusOK)
+
+    ctx, cancel := context.WithCancel(r.Context())
+    defer cancel()
+    go func() {
+        select {
+        case <-h.ctx.Done():
+            cancel()
+        case <-ctx.Done():
+        }
+    }()
+
+    h.logger.Info("application event feed opened",
+        zap.String("application_token", applicationToken),
+        zap.String("last_event_id", lastEventID))
+
+    fmt.Fprintf(w, "retry: %d\n\n", eventsRetry.Milliseconds())
+    if feed.Resync {
+        fmt.Fprintf(w, "event: resync\ndata: {\"last_event_id\":%q}\n\n", lastEventID)
+    }
+    err = controller.Flush()
+
+    for err == nil && ctx.Err() == nil {
+        var events []model.ApplicationEvent
+        events, err = feed.Next(ctx)
+        if err != nil {
+            if ctx.Err() == nil {
+                h.logger.Warn("application event feed ended with an error",
+                    zap.Error(err),
+                    zap.String("application_token", applicationToken))
+            }
+            break
+        }
+
+        if len(events) == 0 {
+            // Keeps proxies from closing an idle connection and surfaces
+            // clients that went away.
+            fmt.Fprint(w, ": keep-alive\n\n")
+        }
+        for _, event := range events {
+            fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+        }
+        err = controller.Flush()
+    }
+
+    h.logger.Info("application event feed closed",
+        zap.String("application_token", applicationToken))
+}
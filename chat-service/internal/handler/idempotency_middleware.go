@@ -0,0 +1,102 @@
+package handler
+
+import (
+    "bytes"
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "io"
+    "net/http"
+
+    "go.uber.org/zap"
+
+    "chat-service/internal/service"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// Idempotent makes a create endpoint safe to retry. When the request carries
+// an Idempotency-Key header, the first response is stored and replayed
+// verbatim, status code included, for every later request with the same
+// key, method and path. Failures are not stored, so the key is released for
+// a retry, unless the write may have committed anyway (a commit that failed
+// without confirming its outcome): repeating that request could store it
+// twice. Requests without the header pass through.
+func Idempotent(idempotency *service.IdempotencyService, logger *zap.Logger) func(http.Handler) http.Handler {
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            key := r.Header.Get(idempotencyKeyHeader)
+            if key == "" {
+                next.ServeHTTP(w, r)
+                return
+            }
+
+            body, err := io.ReadAll(r.Body)
+            if err != nil {
+                respondWithServiceError(w, service.InvalidArgument("Invalid request payload"), "")
+                return
+            }
+            r.Body = io.NopCloser(bytes.NewReader(body))
+
+            fingerprint := sha256.Sum256(body)
+            scopedKey := r.Method + " " + r.URL.Path + " " + key
+
+            stored, err := idempotency.Begin(r.Context(), scopedKey, hex.EncodeToString(fingerprint[:]))
+            if err != nil {
+                logger.Info("rejected idempotent request",
+                    zap.Error(err),
+                    zap.String("idempotency_key", key))
+                respondWithServiceError(w, err, "Failed to process Idempotency-Key")
+                return
+            }
+            if stored != nil {
+                w.Header().Set("Content-Type", "application/json")
+                w.Header().Set("Idempotent-Replayed", "true")
+                w.WriteHeader(stored.Status)
+                w.Write(stored.Body)
+                return
+            }
+
+            // The outcome must be recorded even if the client went away
+            // while the handler was running.
+            ctx := context.WithoutCancel(r.Context())
+
+            // A panicking handler frees the key before the panic goes on
+            // to net/http.
+            defer func() {
+                if p := recover(); p != nil {
+                    idempotency.Release(ctx, scopedKey)
+                    panic(p)
+                }
+            }()
+
+            recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+            next.ServeHTTP(recorder, r)
+
+            if recorder.status < 400 || service.MayHaveCommitted(recorder.err) {
+                idempotency.Complete(ctx, scopedKey, hex.EncodeToString(fingerprint[:]), recorder.status, recorder.body.Bytes())
+            } else {
+                idempotency.Release(ctx, scopedKey)
+            }
+        })
+    }
+}
+
+// responseRecorder passes a response through while keeping a copy of its
+// status and body, and the error it reports if any.
+type responseRecorder struct {
+    http.ResponseWriter
+    status int
+    body   bytes.Buffer
+    err    error
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+    r.status = status
+    r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+    r.body.Write(b)
+    return r.ResponseWriter.Write(b)
+}
@@ -6,7 +6,7 @@ import (
     "go.uber.org/zap"
     "github.com/gorilla/mux"
     
-    // "chat-service/internal/model"
+    "chat-service/internal/model"
     "chat-service/internal/service"
     "chat-service/internal/util"
 )
@@ -27,6 +27,14 @@ type CreateMessageRequest struct {
     Body string `json:"body"`
 }
 
+type CreateMessagesBatchRequest struct {
+    Messages []CreateMessageRequest `json:"messages"`
+}
+
+type UpdateMessageRequest struct {
+    Body string `json:"body"`
+}
+
 
 // @Summary     Create a message
 // @Description Creates a new message in a specific chat
@@ -36,10 +44,13 @@ type CreateMessageRequest struct {
 // @Param       token  path string true "Application Token"
 // @Param       number path int    true "Chat Number"
 // @Param       body   body CreateMessageRequest true "Message Content"
+// @Param       Idempotency-Key header string false "Key that makes retries return the original response"
 // @Success     201 {object} model.CreateMessageResponse
 // @Failure     400 {object} model.ErrorResponse
 // @Failure     404 {object} model.ErrorResponse
+// @Failure     409 {object} model.ErrorResponse
 // @Failure     500 {object} model.ErrorResponse
+// @Failure     503 {object} model.ErrorResponse
 // @Router      /applications/{token}/chats/{number}/messages [post]
 func (h *MessageHandler) Create(w http.ResponseWriter, r *http.Request) {
     vars := mux.Vars(r)
@@ -50,7 +61,7 @@ func (h *MessageHandler) Create(w http.ResponseWriter, r *http.Request) {
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
         h.logger.Error("failed to decode request body",
             zap.Error(err))
-        util.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+        respondWithServiceError(w, service.InvalidArgument("Invalid request payload"), "")
         return
     }
 
@@ -60,7 +71,7 @@ func (h *MessageHandler) Create(w http.ResponseWriter, r *http.Request) {
             zap.Error(err),
             zap.String("application_token", applicationToken),
             zap.String("chat_number", chatNumber))
-        util.RespondWithError(w, http.StatusInternalServerError, "Failed to create message")
+        respondWithServiceError(w, err, "Failed to create message")
         return
     }
 
@@ -70,77 +81,323 @@ func (h *MessageHandler) Create(w http.ResponseWriter, r *http.Request) {
 }
 
 
-// @Summary     List messages
-// @Description Retrieves all messages from a specific chat
+// @Summary     Create messages in bulk
+// @Description Creates up to 500 messages in a chat with consecutive numbers, returned in request order
 // @Tags        messages
+// @Accept      json
 // @Produce     json
 // @Param       token  path string true "Application Token"
 // @Param       number path int    true "Chat Number"
-// @Success     200 {array} model.MessageResponse
+// @Param       body   body CreateMessagesBatchRequest true "Messages"
+// @Param       Idempotency-Key header string false "Key that makes retries return the original response"
+// @Success     201 {object} model.CreateMessagesBatchResponse
+// @Failure     400 {object} model.ErrorResponse
 // @Failure     404 {object} model.ErrorResponse
+// @Failure     409 {object} model.ErrorResponse
 // @Failure     500 {object} model.ErrorResponse
+// @Failure     503 {object} model.ErrorResponse
+// @Router      /applications/{token}/chats/{number}/messages:batch [post]
+func (h *MessageHandler) CreateBatch(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    applicationToken := vars["token"]
+    chatNumber := vars["number"]
+
+    var req CreateMessagesBatchRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        h.logger.Error("failed to decode request body",
+            zap.Error(err))
+        respondWithServiceError(w, service.InvalidArgument("Invalid request payload"), "")
+        return
+    }
+
+    bodies := make([]string, len(req.Messages))
+    for i, message := range req.Messages {
+        bodies[i] = message.Body
+    }
+
+    messages, err := h.service.CreateMessages(r.Context(), applicationToken, chatNumber, bodies)
+    if err != nil {
+        h.logger.Error("failed to create messages",
+            zap.Error(err),
+            zap.String("application_token", applicationToken),
+            zap.String("chat_number", chatNumber),
+            zap.Int("count", len(bodies)))
+        respondWithServiceError(w, err, "Failed to create messages")
+        return
+    }
+
+    numbers := make([]int, len(messages))
+    for i, message := range messages {
+        numbers[i] = message.Number
+    }
+
+    util.RespondWithJSON(w, http.StatusCreated, map[string]interface{}{
+        "message_numbers": numbers,
+    })
+}
+
+// @Summary     Get a message
+// @Description Retrieves a single message of a chat by its number
+// @Tags        messages
+// @Produce     json
+// @Param       token          path string true "Application Token"
+// @Param       number         path int    true "Chat Number"
+// @Param       message_number path int    true "Message Number"
+// @Success     200 {object} model.MessageResponse
+// @Failure     404 {object} model.ErrorResponse
+// @Failure     500 {object} model.ErrorResponse
+// @Failure     503 {object} model.ErrorResponse
+// @Router      /applications/{token}/chats/{number}/messages/{message_number} [get]
+func (h *MessageHandler) Get(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    applicationToken := vars["token"]
+    chatNumber := vars["number"]
+    messageNumber := vars["message_number"]
+
+    message, err := h.service.GetMessage(r.Context(), applicationToken, chatNumber, messageNumber)
+    if err != nil {
+        h.logger.Error("failed to get message",
+            zap.Error(err),
+            zap.String("application_token", applicationToken),
+            zap.String("chat_number", chatNumber),
+            zap.String("message_number", messageNumber))
+        respondWithServiceError(w, err, "Failed to get message")
+        return
+    }
+
+    util.RespondWithJSON(w, http.StatusOK, message)
+}
+
+// @Summary     Edit a message
+// @Description Replaces the body of a message, re-indexes it and publishes message_updated
+// @Tags        messages
+// @Accept      json
+// @Produce     json
+// @Param       token          path string true "Application Token"
+// @Param       number         path int    true "Chat Number"
+// @Param       message_number path int    true "Message Number"
+// @Param       body           body UpdateMessageRequest true "New Message Content"
+// @Success     200 {object} model.MessageResponse
+// @Failure     400 {object} model.ErrorResponse
+// @Failure     404 {object} model.ErrorResponse
+// @Failure     500 {object} model.ErrorResponse
+// @Failure     503 {object} model.ErrorResponse
+// @Router      /applications/{token}/chats/{number}/messages/{message_number} [patch]
+func (h *MessageHandler) Update(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    applicationToken := vars["token"]
+    chatNumber := vars["number"]
+    messageNumber := vars["message_number"]
+
+    var req UpdateMessageRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        h.logger.Error("failed to decode request body",
+            zap.Error(err))
+        respondWithServiceError(w, service.InvalidArgument("Invalid request payload"), "")
+        return
+    }
+    if req.Body == "" {
+        respondWithServiceError(w, service.InvalidArgument("Message body is required"), "")
+        return
+    }
+
+    message, err := h.service.UpdateMessage(r.Context(), applicationToken, chatNumber, messageNumber, req.Body)
+    if err != nil {
+        h.logger.Error("failed to update message",
+            zap.Error(err),
+            zap.String("application_token", applicationToken),
+            zap.String("chat_number", chatNumber),
+            zap.String("message_number", messageNumber))
+        respondWithServiceError(w, err, "Failed to update message")
+        return
+    }
+
+    util.RespondWithJSON(w, http.StatusOK, message)
+}
+
+// @Summary     Delete a message
+// @Description Deletes a message, removes it from the search index and publishes message_deleted
+// @Tags        messages
+// @Param       token          path string true "Application Token"
+// @Param       number         path int    true "Chat Number"
+// @Param       message_number path int    true "Message Number"
+// @Success     204
+// @Failure     404 {object} model.ErrorResponse
+// @Failure     500 {object} model.ErrorResponse
+// @Failure     503 {object} model.ErrorResponse
+// @Router      /applications/{token}/chats/{number}/messages/{message_number} [delete]
+func (h *MessageHandler) Delete(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    applicationToken := vars["token"]
+    chatNumber := vars["number"]
+    messageNumber := vars["message_number"]
+
+    if err := h.service.DeleteMessage(r.Context(), applicationToken, chatNumber, messageNumber); err != nil {
+        h.logger.Error("failed to delete message",
+            zap.Error(err),
+            zap.String("application_token", applicationToken),
+            zap.String("chat_number", chatNumber),
+            zap.String("message_number", messageNumber))
+        respondWithServiceError(w, err, "Failed to delete message")
+        return
+    }
+
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary     List messages
+// @Description Retrieves one page of messages from a specific chat using keyset pagination
+// @Tags        messages
+// @Produce     json
+// @Param       token  path  string true  "Application Token"
+// @Param       number path  int    true  "Chat Number"
+// @Param       limit  query int    false "Page size (1-200, default 50)"
+// @Param       after  query string false "Cursor returned as next_cursor"
+// @Param       before query string false "Cursor returned as prev_cursor"
+// @Param       order  query string false "asc (oldest first, default) or desc (latest first)"
+// @Success     200 {object} model.MessageListResponse
+// @Failure     400 {object} model.ErrorResponse
+// @Failure     404 {object} model.ErrorResponse
+// @Failure     500 {object} model.ErrorResponse
+// @Failure     503 {object} model.ErrorResponse
 // @Router      /applications/{token}/chats/{number}/messages [get]
 func (h *MessageHandler) List(w http.ResponseWriter, r *http.Request) {
     vars := mux.Vars(r)
     applicationToken := vars["token"]
     chatNumber := vars["number"]
 
+    page, err := parsePageQuery(r)
+    if err != nil {
+        respondWithServiceError(w, err, "")
+        return
+    }
+
     h.logger.Info("listing messages",
         zap.String("application_token", applicationToken),
         zap.String("chat_number", chatNumber))
 
-    messages, err := h.service.ListMessages(r.Context(), applicationToken, chatNumber)
+    result, err := h.service.ListMessages(r.Context(), applicationToken, chatNumber, page)
     if err != nil {
         h.logger.Error("failed to list messages",
             zap.Error(err),
             zap.String("application_token", applicationToken),
             zap.String("chat_number", chatNumber))
-        util.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch messages")
+        respondWithServiceError(w, err, "Failed to fetch messages")
         return
     }
 
-    util.RespondWithJSON(w, http.StatusOK, messages)
+    util.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+        "messages":    result.Messages,
+        "next_cursor": result.NextCursor,
+        "prev_cursor": result.PrevCursor,
+    })
 }
 
 // @Summary Search messages
-// @Description Search for messages within a chat based on query text
+// @Description Search for messages within a chat based on query text, newest first, with highlighted body fragments
 // @Tags messages
 // @Accept json
 // @Produce json
 // @Param token path string true "Application Token"
 // @Param number path int true "Chat Number"
 // @Param q query string true "Search Query"
-// @Success 200 {array} model.MessageResponse
-// @Failure 400 {object} model.ErrorResponse "Search query is required"  
+// @Param mode query string false "match (default), phrase, prefix, wildcard or query"
+// @Param sort query string false "newest (default), oldest or relevance"
+// @Param fuzzy query string false "auto, 0, 1 or 2; mode=match only"
+// @Param page query int false "1-based page number (default 1)"
+// @Param size query int false "Page size (1-100, default 10)"
+// @Param created_after query string false "Only messages created at or after this RFC 3339 time"
+// @Param created_before query string false "Only messages created at or before this RFC 3339 time"
+// @Success 200 {object} model.MessageSearchResponse
+// @Header 200 {string} Search-Degraded "mysql-fulltext when Elasticsearch was unavailable and MySQL answered"
+// @Failure 400 {object} model.ErrorResponse "Invalid search parameters"  
 // @Failure 404 {object} model.ErrorResponse "Chat not found"
 // @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Failure 503 {object} model.ErrorResponse "Search backend unavailable"
 // @Router /applications/{token}/chats/{number}/messages/search [get]
 func (h *MessageHandler) Search(w http.ResponseWriter, r *http.Request) {
     vars := mux.Vars(r)
     applicationToken := vars["token"]
     chatNumber := vars["number"]
-    query := r.URL.Query().Get("q")
 
-    if query == "" {
-        util.RespondWithError(w, http.StatusBadRequest, "Search query is required")
+    search, err := parseSearchQuery(r)
+    if err != nil {
+        respondWithServiceError(w, err, "")
         return
     }
 
     h.logger.Info("searching messages",
         zap.String("application_token", applicationToken),
         zap.String("chat_number", chatNumber),
-        zap.String("query", query))
+        zap.String("query", search.Text),
+        zap.Int("page", search.Page),
+        zap.Int("size", search.Size))
 
-    messages, err := h.service.SearchMessages(r.Context(), applicationToken, chatNumber, query)
+    result, err := h.service.SearchMessages(r.Context(), applicationToken, chatNumber, search)
     if err != nil {
         h.logger.Error("failed to search messages",
             zap.Error(err),
             zap.String("application_token", applicationToken),
             zap.String("chat_number", chatNumber),
-            zap.String("query", query))
-        util.RespondWithError(w, http.StatusInternalServerError, "Failed to search messages")
+            zap.String("query", search.Text))
+        respondWithServiceError(w, err, "Failed to search messages")
+        return
+    }
+
+    respondWithSearchResult(w, result)
+}
+
+// @Summary Search messages of an application
+// @Description Search the messages of every chat of an application, newest first, with highlighted body fragments. Each hit carries its chat number.
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param token path string true "Application Token"
+// @Param q query string true "Search Query"
+// @Param mode query string false "match (default), phrase, prefix, wildcard or query"
+// @Param sort query string false "newest (default), oldest or relevance"
+// @Param fuzzy query string false "auto, 0, 1 or 2; mode=match only"
+// @Param page query int false "1-based page number (default 1)"
+// @Param size query int false "Page size (1-100, default 10)"
+// @Param created_after query string false "Only messages created at or after this RFC 3339 time"
+// @Param created_before query string false "Only messages created at or before this RFC 3339 time"
+// @Success 200 {object} model.MessageSearchResponse
+// @Header 200 {string} Search-Degraded "mysql-fulltext when Elasticsearch was unavailable and MySQL answered"
+// @Failure 400 {object} model.ErrorResponse "Invalid search parameters"
+// @Failure 404 {object} model.ErrorResponse "Application not found"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Failure 503 {object} model.ErrorResponse "Search backend unavailable"
+// @Router /applications/{token}/messages/search [get]
+func (h *MessageHandler) SearchApplication(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    applicationToken := vars["token"]
+
+    search, err := parseSearchQuery(r)
+    if err != nil {
+        respondWithServiceError(w, err, "")
+        return
+    }
+
+    h.logger.Info("searching application messages",
+        zap.String("application_token", applicationToken),
+        zap.String("query", search.Text),
+        zap.Int("page", search.Page),
+        zap.Int("size", search.Size))
+
+    result, err := h.service.SearchApplicationMessages(r.Context(), applicationToken, search)
+    if err != nil {
+        respondWithServiceError(w, err, "Failed to search messages")
         return
     }
 
-    util.RespondWithJSON(w, http.StatusOK, messages)
-}
\ No newline at end of file
+    respondWithSearchResult(w, result)
+}
+
+// respondWithSearchResult writes a search result, flagging results served
+// by the MySQL fallback with a Search-Degraded header.
+func respondWithSearchResult(w http.ResponseWriter, result *model.SearchResult) {
+    if result.Degraded {
+        w.Header().Set("Search-Degraded", "mysql-fulltext")
+    }
+    util.RespondWithJSON(w, http.StatusOK, result)
+}
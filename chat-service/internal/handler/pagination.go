@@ -0,0 +1,52 @@
+package handler
+
+import (
+    "fmt"
+    "net/http"
+    "strconv"
+
+    "chat-service/internal/model"
+    "chat-service/internal/service"
+)
+
+// parsePageQuery reads the limit, after, before and order query parameters
+// shared by the list endpoints.
+func parsePageQuery(r *http.Request) (model.PageQuery, error) {
+    query := r.URL.Query()
+    page := model.PageQuery{Limit: model.DefaultPageLimit}
+
+    if raw := query.Get("limit"); raw != "" {
+        limit, err := strconv.Atoi(raw)
+        if err != nil || limit < 1 || limit > model.MaxPageLimit {
+            return page, service.InvalidArgument(fmt.Sprintf("limit must be between 1 and %d", model.MaxPageLimit))
+        }
+        page.Limit = limit
+    }
+
+    after, before := query.Get("after"), query.Get("before")
+    if after != "" && before != "" {
+        return page, service.InvalidArgument("after and before cannot be combined")
+    }
+
+    var err error
+    if after != "" {
+        if page.After, err = model.DecodeCursor(after); err != nil {
+            return page, service.InvalidArgument("invalid after cursor")
+        }
+    }
+    if before != "" {
+        if page.Before, err = model.DecodeCursor(before); err != nil {
+            return page, service.InvalidArgument("invalid before cursor")
+        }
+    }
+
+    switch query.Get("order") {
+    case "", "asc":
+    case "desc":
+        page.Descending = true
+    default:
+        return page, service.InvalidArgument("order must be asc or desc")
+    }
+
+    return page, nil
+}
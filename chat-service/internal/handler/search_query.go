@@ -0,0 +1,192 @@
+package handler
+
+import (
+    "fmt"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+
+    "chat-service/internal/model"
+    "chat-service/internal/service"
+)
+
+const maxSearchTextLength = 1024
+
+// parseSearchQuery reads the q, mode, sort, fuzzy, page, size, created_after
+// and created_before query parameters of the search endpoints.
+func parseSearchQuery(r *http.Request) (model.SearchQuery, error) {
+    query := r.URL.Query()
+    search := model.SearchQuery{
+        Text: strings.TrimSpace(query.Get("q")),
+        Mode: model.SearchModeMatch,
+        Sort: model.SearchSortNewest,
+        Page: 1,
+        Size: model.DefaultSearchSize,
+    }
+
+    if search.Text == "" {
+        return search, service.InvalidArgument("Search query is required")
+    }
+    if len(search.Text) > maxSearchTextLength {
+        return search, service.InvalidArgument(fmt.Sprintf("q must be at most %d characters", maxSearchTextLength))
+    }
+
+    if raw := query.Get("mode"); raw != "" {
+        search.Mode = model.SearchMode(raw)
+        switch search.Mode {
+        case model.SearchModeMatch, model.SearchModePhrase, model.SearchModePrefix:
+        case model.SearchModeWildcard:
+            if err := validateWildcard(search.Text); err != nil {
+                return search, err
+            }
+        case model.SearchModeQuery:
+            if err := validateQueryString(search.Text); err != nil {
+                return search, err
+            }
+        default:
+            return search, service.InvalidArgument("mode must be one of match, phrase, prefix, wildcard, query")
+        }
+    }
+
+    if raw := query.Get("sort"); raw != "" {
+        search.Sort = model.SearchSort(raw)
+        switch search.Sort {
+        case model.SearchSortNewest, model.SearchSortOldest, model.SearchSortRelevance:
+        default:
+            return search, service.InvalidArgument("sort must be one of newest, oldest, relevance")
+        }
+    }
+
+    if raw := query.Get("fuzzy"); raw != "" {
+        if search.Mode != model.SearchModeMatch {
+            return search, service.InvalidArgument("fuzzy is only supported with mode=match; use term~ in mode=query")
+        }
+        switch strings.ToLower(raw) {
+        case "auto":
+            search.Fuzziness = "AUTO"
+        case "0", "1", "2":
+            search.Fuzziness = raw
+        default:
+            return search, service.InvalidArgument("fuzzy must be auto, 0, 1 or 2")
+        }
+    }
+
+    if raw := query.Get("page"); raw != "" {
+        page, err := strconv.Atoi(raw)
+        if err != nil || page < 1 {
+            return search, service.InvalidArgument("page must be a positive integer")
+        }
+        search.Page = page
+    }
+
+    if raw := query.Get("size"); raw != "" {
+        size, err := strconv.Atoi(raw)
+        if err != nil || size < 1 || size > model.MaxSearchSize {
+            return search, service.InvalidArgument(fmt.Sprintf("size must be between 1 and %d", model.MaxSearchSize))
+        }
+        search.Size = size
+    }
+
+    if search.Page*search.Size > model.MaxSearchWindow {
+        return search, service.InvalidArgument(fmt.Sprintf("page * size cannot exceed %d", model.MaxSearchWindow))
+    }
+
+    for _, bound := range []struct {
+        name   string
+        target **time.Time
+    }{
+        {"created_after", &search.CreatedAfter},
+        {"created_before", &search.CreatedBefore},
+    } {
+        raw := query.Get(bound.name)
+        if raw == "" {
+            continue
+        }
+        t, err := time.Parse(time.RFC3339, raw)
+        if err != nil {
+            return search, service.InvalidArgument(fmt.Sprintf("%s must be an RFC 3339 timestamp", bound.name))
+        }
+        *bound.target = &t
+    }
+
+    if search.CreatedAfter != nil && search.CreatedBefore != nil && search.CreatedAfter.After(*search.CreatedBefore) {
+        return search, service.InvalidArgument("created_after must not be later than created_before")
+    }
+
+    return search, nil
+}
+
+// validateWildcard accepts a single word with * and ? wildcards. A leading
+// wildcard would make Elasticsearch scan every term of the index.
+func validateWildcard(pattern string) error {
+    if strings.ContainsAny(pattern, " \t\n") {
+        return service.InvalidArgument("mode=wildcard takes a single word")
+    }
+    if strings.HasPrefix(pattern, "*") || strings.HasPrefix(pattern, "?") {
+        return service.InvalidArgument("mode=wildcard patterns cannot start with a wildcard")
+    }
+    return nil
+}
+
+// validateQueryString rejects query string syntax Elasticsearch would fail
+// to parse, plus leading wildcards and field qualifiers: the query always
+// runs against the message body.
+func validateQueryString(text string) error {
+    depth := 0
+    inPhrase := false
+    escaped := false
+    expectTerm := true
+
+    for _, word := range strings.Fields(text) {
+        if !inPhrase {
+            switch word {
+            case "AND", "OR":
+                if expectTerm {
+                    return service.InvalidArgument(fmt.Sprintf("%s must be placed between two terms", word))
+                }
+                expectTerm = true
+                continue
+            case "NOT":
+                expectTerm = true
+                continue
+            }
+            trimmed := strings.TrimLeft(word, "(+-")
+            if strings.HasPrefix(trimmed, "*") || strings.HasPrefix(trimmed, "?") {
+                return service.InvalidArgument("query terms cannot start with a wildcard")
+            }
+        }
+
+        for _, c := range word {
+            switch {
+            case escaped:
+                escaped = false
+            case c == '\\':
+                escaped = true
+            case c == '"':
+                inPhrase = !inPhrase
+            case inPhrase:
+            case c == '(':
+                depth++
+            case c == ')':
+                depth--
+                if depth < 0 {
+                    return service.InvalidArgument("query has an unmatched )")
+                }
+            case c == ':' || c == '[' || c == ']' || c == '{' || c == '}' || c == '/':
+                return service.InvalidArgument(fmt.Sprintf("query cannot contain %q; escape it with \\", c))
+            }
+        }
+        expectTerm = false
+    }
+
+    switch {
+    case inPhrase:
+        return service.InvalidArgument("query has an unterminated phrase")
+    case depth > 0:
+        return service.InvalidArgument("query has an unmatched (")
+    case expectTerm:
+        return service.InvalidArgument("query cannot end with an operator")
+    }
+    return nil
+}
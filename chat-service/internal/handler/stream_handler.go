@@ -0,0 +1,152 @@
+package handler
+
+import (
+    "context"
+    "net/http"
+    "strconv"
+    "time"
+
+    "github.com/gorilla/mux"
+    "github.com/gorilla/websocket"
+    "go.uber.org/zap"
+
+    "chat-service/internal/model"
+    "chat-service/internal/service"
+)
+
+const (
+    streamWriteWait  = 10 * time.Second
+    streamPongWait   = 60 * time.Second
+    streamPingPeriod = streamPongWait * 9 / 10
+)
+
+// StreamHandler serves the real-time WebSocket feeds. Hijacked connections
+// outlive http.Server.Shutdown, so Close has to end them explicitly.
+type StreamHandler struct {
+    service  *service.MessageService
+    logger   *zap.Logger
+    upgrader websocket.Upgrader
+    ctx      context.Context
+    cancel   context.CancelFunc
+}
+
+func NewStreamHandler(service *service.MessageService, logger *zap.Logger) *StreamHandler {
+    ctx, cancel := context.WithCancel(context.Background())
+    return &StreamHandler{
+        service: service,
+        logger:  logger,
+        upgrader: websocket.Upgrader{
+            ReadBufferSize:  1024,
+            WriteBufferSize: 4096,
+        },
+        ctx:    ctx,
+        cancel: cancel,
+    }
+}
+
+// Close ends every open stream.
+func (h *StreamHandler) Close() {
+    h.cancel()
+}
+
+// @Summary     Stream chat messages
+// @Description Upgrades to a WebSocket that pushes {"type", "message"} events for messages created, edited or deleted in the chat. Pass after to first receive every message with a greater number, e.g. the last number seen before a reconnect.
+// @Tags        messages
+// @Param       token  path  string true  "Application Token"
+// @Param       number path  int    true  "Chat Number"
+// @Param       after  query int    false "Replay messages with a greater number before streaming"
+// @Success     101
+// @Failure     400 {object} model.ErrorResponse
+// @Failure     404 {object} model.ErrorResponse
+// @Failure     503 {object} model.ErrorResponse
+// @Router      /applications/{token}/chats/{number}/stream [get]
+func (h *StreamHandler) Stream(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    applicationToken := vars["token"]
+    chatNumber := vars["number"]
+
+    var after *int
+    if raw := r.URL.Query().Get("after"); raw != "" {
+        number, err := strconv.Atoi(raw)
+        if err != nil || number < 0 {
+            respondWithServiceError(w, service.InvalidArgument("after must be a non-negative message number"), "")
+            return
+        }
+        after = &number
+    }
+
+    stream, err := h.service.OpenStream(r.Context(), applicationToken, chatNumber)
+    if err != nil {
+        respondWithServiceError(w, err, "Failed to open chat stream")
+        return
+    }
+    defer stream.Close()
+
+    conn, err := h.upgrader.Upgrade(w, r, nil)
+    if err != nil {
+        // Upgrade has already answered the client.
+        h.logger.Info("failed to upgrade chat stream", zap.Error(err))
+        return
+    }
+    defer conn.Close()
+
+    h.logger.Info("chat stream opened",
+        zap.String("application_token", applicationToken),
+        zap.String("chat_number", chatNumber))
+
+    ctx, cancel := context.WithCancel(h.ctx)
+    defer cancel()
+
+    // Clients only send control frames; reading is what processes pongs
+    // and notices a closed connection.
+    conn.SetReadLimit(512)
+    conn.SetReadDeadline(time.Now().Add(streamPongWait))
+    conn.SetPongHandler(func(string) error {
+        return conn.SetReadDeadline(time.Now().Add(streamPongWait))
+    })
+    go func() {
+        defer cancel()
+        for {
+            if _, _, err := conn.ReadMessage(); err != nil {
+                return
+            }
+        }
+    }()
+
+    go func() {
+        ticker := time.NewTicker(streamPingPeriod)
+        defer ticker.Stop()
+        for {
+            select {
+            case <-ctx.Done():
+                return
+            case <-ticker.C:
+                if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteWait)); err != nil {
+                    cancel()
+                    return
+                }
+            }
+        }
+    }()
+
+    err = stream.Run(ctx, after, func(event *model.ChatStreamEvent) error {
+        conn.SetWriteDeadline(time.Now().Add(streamWriteWait))
+        return conn.WriteJSON(event)
+    })
+
+    closeCode, reason := websocket.CloseNormalClosure, ""
+    if err != nil {
+        h.logger.Warn("chat stream ended with an error",
+            zap.Error(err),
+            zap.String("application_token", applicationToken),
+            zap.String("chat_number", chatNumber))
+        closeCode, reason = websocket.CloseTryAgainLater, "stream interrupted, reconnect with after"
+    }
+    conn.WriteControl(websocket.CloseMessage,
+        websocket.FormatCloseMessage(closeCode, reason),
+        time.Now().Add(streamWriteWait))
+
+    h.logger.Info("chat stream closed",
+        zap.String("application_token", applicationToken),
+        zap.String("chat_number", chatNumber))
+}
@@ -0,0 +1,13 @@
+package model
+
+import "encoding/json"
+
+// ApplicationEvent is one entry of an application's activity feed. Type is
+// EventChatCreated or EventMessageCreated and Data is the chat or message
+// exactly as published to RabbitMQ. ID orders events and is used to resume
+// the feed.
+type ApplicationEvent struct {
+    ID   string
+    Type string
+    Data json.RawMessage
+}
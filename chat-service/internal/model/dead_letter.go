@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+const (
+    DefaultDeadLetterLimit = 20
+    MaxDeadLetterLimit     = 500
+)
+
+// DeadLetter is an event parked in the dead-letter queue of a work queue
+// after it could not be processed.
+type DeadLetter struct {
+    Exchange       string
+    Body           string
+    Error          string
+    Retries        int64
+    DeadLetteredAt time.Time
+}
@@ -0,0 +1,57 @@
+package model
+
+import (
+    "time"
+
+    "chat-service/pkg/events"
+)
+
+const (
+    EventChatCreated    = "chat_created"
+    EventMessageCreated = "message_created"
+    EventMessageUpdated = "message_updated"
+    EventMessageDeleted = "message_deleted"
+)
+
+// OutboxEvent is a domain event persisted in the same transaction as the
+// row it describes, waiting to be relayed to RabbitMQ.
+type OutboxEvent struct {
+    ID        uint64
+    EventType string
+    Payload   []byte
+    Attempts  int
+    CreatedAt time.Time
+}
+
+// CounterEvent is a counter change derived from a consumed event. Key
+// uniquely identifies the source event so redeliveries are applied once;
+// Delta is +1 for creations and -1 for deletions.
+type CounterEvent struct {
+    Key              string
+    ApplicationToken string
+    ChatID           uint64
+    Delta            int
+}
+
+// ChatEventData is the data of the chat_created event of chat. RabbitMQ
+// and the application feed both get it, so they share one schema.
+func ChatEventData(chat *Chat) events.Chat {
+    return events.Chat{
+        ID:            chat.ID,
+        ApplicationID: chat.ApplicationID,
+        Number:        chat.Number,
+        MessagesCount: chat.MessagesCount,
+        CreatedAt:     chat.CreatedAt,
+    }
+}
+
+// MessageEventData is the data of the events about message.
+func MessageEventData(message *Message) events.Message {
+    return events.Message{
+        ID:        message.ID,
+        ChatID:    message.ChatID,
+        Number:    message.Number,
+        Body:      message.Body,
+        CreatedAt: message.CreatedAt,
+    }
+}
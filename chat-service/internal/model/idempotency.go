@@ -0,0 +1,15 @@
+package model
+
+import (
+    "encoding/json"
+)
+
+// IdempotentResponse is what is remembered for an Idempotency-Key. While the
+// first request is still running Completed is false and only Fingerprint is
+// set; afterwards Status and Body hold the response to replay.
+type IdempotentResponse struct {
+    Fingerprint string          `json:"fingerprint"`
+    Completed   bool            `json:"completed"`
+    Status      int             `json:"status,omitempty"`
+    Body        json.RawMessage `json:"body,omitempty"`
+}
@@ -6,6 +6,10 @@ import (
     "time"
 )
 
+// MaxMessageBatchSize caps the number of messages accepted by one batch
+// create request.
+const MaxMessageBatchSize = 500
+
 type Message struct {
     ID        uint64    `json:"id"`
     ChatID    uint64    `json:"chat_id"`
@@ -0,0 +1,57 @@
+package model
+
+import (
+    "encoding/base64"
+    "fmt"
+    "strconv"
+    "strings"
+)
+
+const (
+    DefaultPageLimit = 50
+    MaxPageLimit     = 200
+)
+
+// PageQuery describes one page of a keyset-paginated listing ordered by
+// number. After and Before are exclusive bounds expressed in the listing's
+// own order; at most one of them is set, zero meaning no bound.
+type PageQuery struct {
+    Limit      int
+    After      int
+    Before     int
+    Descending bool
+}
+
+type MessagePage struct {
+    Messages   []*Message
+    NextCursor string
+    PrevCursor string
+}
+
+type ChatPage struct {
+    Chats      []*Chat
+    NextCursor string
+    PrevCursor string
+}
+
+const cursorPrefix = "n:"
+
+// EncodeCursor turns a resource number into an opaque page cursor.
+func EncodeCursor(number int) string {
+    return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(number)))
+}
+
+// DecodeCursor reverses EncodeCursor.
+func DecodeCursor(cursor string) (int, error) {
+    raw, err := base64.RawURLEncoding.DecodeString(cursor)
+    if err != nil || !strings.HasPrefix(string(raw), cursorPrefix) {
+        return 0, fmt.Errorf("malformed cursor")
+    }
+
+    number, err := strconv.Atoi(strings.TrimPrefix(string(raw), cursorPrefix))
+    if err != nil || number <= 0 {
+        return 0, fmt.Errorf("malformed cursor")
+    }
+
+    return number, nil
+}
@@ -22,6 +22,60 @@ type MessageResponse struct {
     CreatedAt time.Time `json:"created_at" example:"2024-11-19T20:00:00Z"`
 }
 
+type CreateMessagesBatchResponse struct {
+    MessageNumbers []int `json:"message_numbers" example:"4,5,6"`
+}
+
+type MessageSearchHitResponse struct {
+    MessageResponse
+    ChatNumber int      `json:"chat_number" example:"3"`
+    Highlights []string `json:"highlights" example:"Welcome to <em>instabug</em>!!"`
+}
+
+type MessageSearchResponse struct {
+    Total    int64                      `json:"total" example:"42"`
+    Page     int                        `json:"page" example:"1"`
+    Size     int                        `json:"size" example:"10"`
+    Messages []MessageSearchHitResponse `json:"messages"`
+}
+
+type MessageListResponse struct {
+    Messages   []MessageResponse `json:"messages"`
+    NextCursor string            `json:"next_cursor,omitempty" example:"bjo1MQ"`
+    PrevCursor string            `json:"prev_cursor,omitempty" example:"bjoy"`
+}
+
+type ChatResponse struct {
+    Number        int       `json:"number" example:"1"`
+    MessagesCount int       `json:"messages_count" example:"12"`
+    CreatedAt     time.Time `json:"created_at" example:"2024-11-19T20:00:00Z"`
+}
+
+type ChatListResponse struct {
+    Chats      []ChatResponse `json:"chats"`
+    NextCursor string         `json:"next_cursor,omitempty" example:"bjo1MQ"`
+    PrevCursor string         `json:"prev_cursor,omitempty" example:"bjoy"`
+}
+
 type ErrorResponse struct {
     Error string `json:"error" example:"Error message"`
+    Code  string `json:"code" example:"chat_not_found"`
+}
+
+type DeadLetterResponse struct {
+    Exchange       string    `json:"exchange" example:"message_created"`
+    Body           string    `json:"body" example:"{\"id\":12,\"chat_id\":3}"`
+    Error          string    `json:"error" example:"failed to apply counter increments"`
+    Retries        int64     `json:"retries" example:"5"`
+    DeadLetteredAt time.Time `json:"dead_lettered_at" example:"2024-11-19T20:00:00Z"`
+}
+
+type DeadLetterListResponse struct {
+    Queue       string               `json:"queue" example:"counters.message_created"`
+    DeadLetters []DeadLetterResponse `json:"dead_letters"`
+}
+
+type ReplayDeadLettersResponse struct {
+    Queue    string `json:"queue" example:"counters.message_created"`
+    Replayed int    `json:"replayed" example:"12"`
 }
@@ -0,0 +1,94 @@
+package model
+
+import (
+    "time"
+)
+
+const (
+    DefaultSearchSize = 10
+    MaxSearchSize     = 100
+    // MaxSearchWindow mirrors Elasticsearch's index.max_result_window.
+    MaxSearchWindow = 10000
+)
+
+// SearchMode selects how the q parameter of a search is interpreted.
+type SearchMode string
+
+const (
+    // SearchModeMatch finds messages containing any of the words.
+    SearchModeMatch SearchMode = "match"
+    // SearchModePhrase finds the words next to each other, in order.
+    SearchModePhrase SearchMode = "phrase"
+    // SearchModePrefix is SearchModePhrase with the last word as a prefix.
+    SearchModePrefix SearchMode = "prefix"
+    // SearchModeWildcard matches a single word against a pattern with *
+    // and ? wildcards.
+    SearchModeWildcard SearchMode = "wildcard"
+    // SearchModeQuery accepts query string syntax: AND, OR, NOT, "phrases",
+    // prefix*, typo~ and parentheses.
+    SearchModeQuery SearchMode = "query"
+)
+
+// SearchSort orders search hits.
+type SearchSort string
+
+const (
+    SearchSortNewest    SearchSort = "newest"
+    SearchSortOldest    SearchSort = "oldest"
+    SearchSortRelevance SearchSort = "relevance"
+)
+
+// SearchQuery is a full-text search over message bodies. Page is 1-based;
+// CreatedAfter and CreatedBefore are optional inclusive bounds. Fuzziness
+// is "AUTO", "0", "1", "2" or empty and only applies to SearchModeMatch.
+type SearchQuery struct {
+    Text          string
+    Mode          SearchMode
+    Sort          SearchSort
+    Fuzziness     string
+    Page          int
+    Size          int
+    CreatedAfter  *time.Time
+    CreatedBefore *time.Time
+}
+
+// SearchScope restricts a search to one chat, or to every chat of an
+// application when ChatID is zero.
+type SearchScope struct {
+    ApplicationToken string
+    ChatID           uint64
+}
+
+// MessageDocument is a message as stored in the search index. The
+// application token and chat number are copied from the chat so searches
+// can span every chat of an application and still say where a hit lives.
+type MessageDocument struct {
+    Message
+    ApplicationToken string `json:"application_token"`
+    ChatNumber       int    `json:"chat_number"`
+}
+
+func NewMessageDocument(chat *Chat, message *Message) *MessageDocument {
+    return &MessageDocument{
+        Message:          *message,
+        ApplicationToken: chat.ApplicationID,
+        ChatNumber:       chat.Number,
+    }
+}
+
+// SearchHit is a matching message along with the highlighted fragments of
+// its body that explain the match.
+type SearchHit struct {
+    *MessageDocument
+    Highlights []string `json:"highlights"`
+}
+
+type SearchResult struct {
+    Total int64        `json:"total"`
+    Page  int          `json:"page"`
+    Size  int          `json:"size"`
+    Hits  []*SearchHit `json:"messages"`
+    // Degraded is set when the result came from the MySQL full-text
+    // fallback instead of Elasticsearch.
+    Degraded bool `json:"-"`
+}
@@ -0,0 +1,17 @@
+package model
+
+// ChatStreamEvent is pushed to clients following a chat in real time. Type
+// is EventMessageCreated, EventMessageUpdated or EventMessageDeleted; a
+// deleted message carries its last known body.
+type ChatStreamEvent struct {
+    Type    string   `json:"type"`
+    Message *Message `json:"message"`
+}
+
+// ChatSubscription delivers the events of one chat until it is closed.
+// Events is closed when the subscription ends, whether by Close or because
+// the connection behind it broke.
+type ChatSubscription interface {
+    Events() <-chan *ChatStreamEvent
+    Close() error
+}
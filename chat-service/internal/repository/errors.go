@@ -0,0 +1,16 @@
+package repository
+
+import (
+    "errors"
+)
+
+// Storage failures that callers may want to tell apart. Repositories wrap
+// the underlying driver error with one of these so errors.Is works across
+// the MySQL, Redis and Elasticsearch backends.
+var (
+    ErrDuplicate   = errors.New("duplicate key")
+    ErrUnavailable = errors.New("storage unavailable")
+    // ErrCommitUnknown marks a commit that failed without confirming its
+    // outcome: the write may or may not have been stored.
+    ErrCommitUnknown = errors.New("commit outcome unknown")
+)
@@ -0,0 +1,97 @@
+// Package memory holds in-process implementations of the repositories, for
+// tests and for running the services without MySQL, Redis or
+// Elasticsearch. They keep the uniqueness and ordering rules of the real
+// stores and report violations with the same repository errors. Values are
+// copied in and out, so callers never share state with the store.
+package memory
+
+import (
+    "context"
+    "fmt"
+    "sync"
+
+    "chat-service/internal/model"
+    "chat-service/internal/repository"
+)
+
+// ChatRepository stores chats, unique by application and number, like the
+// unique_app_number index of the chats table.
+type ChatRepository struct {
+    mu     sync.Mutex
+    lastID uint64
+    // chats maps an application token to its chats by number.
+    chats map[string]map[int]*model.Chat
+}
+
+func NewChatRepository() *ChatRepository {
+    return &ChatRepository{chats: make(map[string]map[int]*model.Chat)}
+}
+
+func (r *ChatRepository) Create(ctx context.Context, chat *model.Chat) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    byNumber := r.chats[chat.ApplicationID]
+    if _, ok := byNumber[chat.Number]; ok {
+        return fmt.Errorf("failed to insert chat: %w", repository.ErrDuplicate)
+    }
+    if byNumber == nil {
+        byNumber = make(map[int]*model.Chat)
+        r.chats[chat.ApplicationID] = byNumber
+    }
+
+    r.lastID++
+    chat.ID = r.lastID
+    stored := *chat
+    byNumber[chat.Number] = &stored
+
+    return nil
+}
+
+// GetByNumber returns nil when no such chat exists.
+func (r *ChatRepository) GetByNumber(ctx context.Context, applicationID string, number int) (*model.Chat, error) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    chat, ok := r.chats[applicationID][number]
+    if !ok {
+        return nil, nil
+    }
+    found := *chat
+    return &found, nil
+}
+
+// ListByApplication returns one keyset page of an application's chats
+// ordered by number.
+func (r *ChatRepository) ListByApplication(ctx context.Context, applicationToken string, page model.PageQuery) ([]*model.Chat, bool, error) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    byNumber := r.chats[applicationToken]
+    numbers := make([]int, 0, len(byNumber))
+    for number := range byNumber {
+        numbers = append(numbers, number)
+    }
+
+    selected, hasMore := keyset(numbers, page)
+
+    var chats []*model.Chat
+    for _, number := range selected {
+        chat := *byNumber[number]
+        chats = append(chats, &chat)
+    }
+    return chats, hasMore, nil
+}
+
+func (r *ChatRepository) MaxChatNumber(ctx context.Context, applicationToken string) (int, error) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    max := 0
+    for number := range r.chats[applicationToken] {
+        if number > max {
+            max = number
+        }
+    }
+    return max, nil
+}
@@ -0,0 +1,226 @@
+package memory
+
+import (
+    "context"
+    "fmt"
+    "sync"
+
+    "chat-service/internal/model"
+    "chat-service/internal/repository"
+)
+
+// MessageRepository stores messages, unique by chat and number like the
+// unique_chat_number index of the messages table, and searches them the
+// way the Elasticsearch index and the MySQL fallback do.
+type MessageRepository struct {
+    mu     sync.Mutex
+    lastID uint64
+    // messages maps a chat id to its messages by number, stored as search
+    // documents so searches know the application and chat number.
+    messages map[uint64]map[int]*model.MessageDocument
+    // searchDown makes Search fail as if Elasticsearch were unreachable.
+    searchDown bool
+}
+
+func NewMessageRepository() *MessageRepository {
+    return &MessageRepository{messages: make(map[uint64]map[int]*model.MessageDocument)}
+}
+
+func (r *MessageRepository) Create(ctx context.Context, chat *model.Chat, message *model.Message) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    if _, ok := r.messages[message.ChatID][message.Number]; ok {
+        return fmt.Errorf("failed to insert message: %w", repository.ErrDuplicate)
+    }
+
+    r.insert(chat, message)
+    return nil
+}
+
+// CreateBatch stores messages of one chat all at once: when any number is
+// taken, or repeated within the batch, none of them is stored.
+func (r *MessageRepository) CreateBatch(ctx context.Context, chat *model.Chat, messages []*model.Message) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    numbers := make(map[int]bool, len(messages))
+    for _, message := range messages {
+        if _, ok := r.messages[chat.ID][message.Number]; ok || numbers[message.Number] {
+            return fmt.Errorf("failed to insert messages: %w", repository.ErrDuplicate)
+        }
+        numbers[message.Number] = true
+    }
+
+    for _, message := range messages {
+        message.ChatID = chat.ID
+        r.insert(chat, message)
+    }
+    return nil
+}
+
+// insert assigns message the next id and stores a copy. r.mu must be held.
+func (r *MessageRepository) insert(chat *model.Chat, message *model.Message) {
+    r.lastID++
+    message.ID = r.lastID
+
+    byNumber := r.messages[message.ChatID]
+    if byNumber == nil {
+        byNumber = make(map[int]*model.MessageDocument)
+        r.messages[message.ChatID] = byNumber
+    }
+    byNumber[message.Number] = model.NewMessageDocument(chat, message)
+}
+
+// GetByNumber returns nil when no such message exists.
+func (r *MessageRepository) GetByNumber(ctx context.Context, chatID uint64, number int) (*model.Message, error) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    doc, ok := r.messages[chatID][number]
+    if !ok {
+        return nil, nil
+    }
+    message := doc.Message
+    return &message, nil
+}
+
+// Update replaces the body of the message identified by (chat, number). It
+// returns nil when no such message exists.
+func (r *MessageRepository) Update(ctx context.Context, chat *model.Chat, number int, body string) (*model.Message, error) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    doc, ok := r.messages[chat.ID][number]
+    if !ok {
+        return nil, nil
+    }
+    doc.Body = body
+    message := doc.Message
+    return &message, nil
+}
+
+// Delete removes the message identified by (chat, number) and returns it,
+// or nil when no such message exists.
+func (r *MessageRepository) Delete(ctx context.Context, chat *model.Chat, number int) (*model.Message, error) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    doc, ok := r.messages[chat.ID][number]
+    if !ok {
+        return nil, nil
+    }
+    delete(r.messages[chat.ID], number)
+    message := doc.Message
+    return &message, nil
+}
+
+// ListByChat returns one keyset page of a chat's messages ordered by number.
+func (r *MessageRepository) ListByChat(ctx context.Context, chatID uint64, page model.PageQuery) ([]*model.Message, bool, error) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    byNumber := r.messages[chatID]
+    numbers := make([]int, 0, len(byNumber))
+    for number := range byNumber {
+        numbers = append(numbers, number)
+    }
+
+    selected, hasMore := keyset(numbers, page)
+
+    var messages []*model.Message
+    for _, number := range selected {
+        message := byNumber[number].Message
+        messages = append(messages, &message)
+    }
+    return messages, hasMore, nil
+}
+
+func (r *MessageRepository) MaxMessageNumber(ctx context.Context, chatID uint64) (int, error) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    max := 0
+    for number := range r.messages[chatID] {
+        if number > max {
+            max = number
+        }
+    }
+    return max, nil
+}
+
+// SetSearchAvailable takes the simulated search index down or brings it
+// back. While it is down Search fails with repository.ErrUnavailable and
+// only SearchFullText answers.
+func (r *MessageRepository) SetSearchAvailable(available bool) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    r.searchDown = !available
+}
+
+// Search stands in for the Elasticsearch query: it returns the requested
+// page of matching messages with the total hit count and the body, with
+// matched words in <em> tags, as the only highlighted fragment.
+func (r *MessageRepository) Search(ctx context.Context, scope model.SearchScope, search model.SearchQuery) (*model.SearchResult, error) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    if r.searchDown {
+        return nil, fmt.Errorf("failed to execute search: %w", repository.ErrUnavailable)
+    }
+    return r.search(scope, search, true), nil
+}
+
+// SearchFullText stands in for the MySQL fallback and, like it, returns no
+// highlights.
+func (r *MessageRepository) SearchFullText(ctx context.Context, scope model.SearchScope, search model.SearchQuery) (*model.SearchResult, error) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    return r.search(scope, search, false), nil
+}
+
+// search runs a query over the stored documents. r.mu must be held.
+func (r *MessageRepository) search(scope model.SearchScope, search model.SearchQuery, highlight bool) *model.SearchResult {
+    matcher := newMatcher(search)
+
+    var matches []searchMatch
+    for chatID, byNumber := range r.messages {
+        if scope.ChatID != 0 && chatID != scope.ChatID {
+            continue
+        }
+        for _, doc := range byNumber {
+            if scope.ChatID == 0 && doc.ApplicationToken != scope.ApplicationToken {
+                continue
+            }
+            if search.CreatedAfter != nil && doc.CreatedAt.Before(*search.CreatedAfter) {
+                continue
+            }
+            if search.CreatedBefore != nil && doc.CreatedAt.After(*search.CreatedBefore) {
+                continue
+            }
+            if match, ok := matcher.match(doc); ok {
+                matches = append(matches, match)
+            }
+        }
+    }
+    sortMatches(matches, search.Sort)
+
+    result := &model.SearchResult{
+        Total: int64(len(matches)),
+        Hits:  []*model.SearchHit{},
+    }
+
+    from := (search.Page - 1) * search.Size
+    for i := from; i >= 0 && i < len(matches) && i < from+search.Size; i++ {
+        doc := *matches[i].doc
+        hit := &model.SearchHit{MessageDocument: &doc, Highlights: []string{}}
+        if highlight {
+            hit.Highlights = []string{matches[i].highlight()}
+        }
+        result.Hits = append(result.Hits, hit)
+    }
+
+    return result
+}
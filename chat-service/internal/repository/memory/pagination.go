@@ -0,0 +1,53 @@
+package memory
+
+import (
+    "sort"
+
+    "chat-service/internal/model"
+)
+
+// keyset picks the numbers of one page out of numbers the way the MySQL
+// keyset queries do: scanning from the cursor in the listing order, or
+// against it for pages fetched backwards, and returning them in display
+// order. hasMore reports whether another page exists in the direction the
+// page was fetched.
+func keyset(numbers []int, page model.PageQuery) (selected []int, hasMore bool) {
+    forward := page.Before == 0
+    descending := forward == page.Descending
+
+    sort.Ints(numbers)
+    if descending {
+        reverse(numbers)
+    }
+
+    cursor := page.After
+    if !forward {
+        cursor = page.Before
+    }
+
+    for _, number := range numbers {
+        if cursor > 0 && (descending && number >= cursor || !descending && number <= cursor) {
+            continue
+        }
+        selected = append(selected, number)
+        if len(selected) > page.Limit {
+            break
+        }
+    }
+
+    hasMore = len(selected) > page.Limit
+    if hasMore {
+        selected = selected[:page.Limit]
+    }
+    if !forward {
+        reverse(selected)
+    }
+
+    return selected, hasMore
+}
+
+func reverse(numbers []int) {
+    for i, j := 0, len(numbers)-1; i < j; i, j = i+1, j-1 {
+        numbers[i], numbers[j] = numbers[j], numbers[i]
+    }
+}
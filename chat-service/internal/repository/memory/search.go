@@ -0,0 +1,179 @@
+package memory
+
+import (
+    "regexp"
+    "sort"
+    "strings"
+    "unicode"
+
+    "chat-service/internal/model"
+)
+
+// Matching approximates the Elasticsearch standard analyzer: bodies and
+// search text are split into lower-cased words of letters and digits.
+// Fuzziness is ignored, and query strings match any of their words, as in
+// the MySQL fallback.
+
+// word is a word of a body with its byte offsets, for highlighting.
+type word struct {
+    text       string
+    start, end int
+}
+
+func tokenize(text string) []word {
+    var words []word
+    start := -1
+    for i, c := range text {
+        inWord := unicode.IsLetter(c) || unicode.IsDigit(c)
+        if inWord && start < 0 {
+            start = i
+        }
+        if !inWord && start >= 0 {
+            words = append(words, word{strings.ToLower(text[start:i]), start, i})
+            start = -1
+        }
+    }
+    if start >= 0 {
+        words = append(words, word{strings.ToLower(text[start:]), start, len(text)})
+    }
+    return words
+}
+
+// queryOperators are the query string keywords that are not search terms.
+var queryOperators = map[string]bool{"AND": true, "OR": true, "NOT": true}
+
+type matcher struct {
+    mode    model.SearchMode
+    terms   []string
+    pattern *regexp.Regexp
+}
+
+func newMatcher(search model.SearchQuery) *matcher {
+    m := &matcher{mode: search.Mode}
+
+    text := search.Text
+    if search.Mode == model.SearchModeQuery {
+        var kept []string
+        for _, field := range strings.Fields(text) {
+            if !queryOperators[field] {
+                kept = append(kept, field)
+            }
+        }
+        text = strings.Join(kept, " ")
+    }
+    for _, w := range tokenize(text) {
+        m.terms = append(m.terms, w.text)
+    }
+
+    if search.Mode == model.SearchModeWildcard {
+        pattern := regexp.QuoteMeta(strings.ToLower(search.Text))
+        pattern = strings.NewReplacer(`\*`, `.*`, `\?`, `.`).Replace(pattern)
+        m.pattern = regexp.MustCompile(`^` + pattern + `$`)
+    }
+
+    return m
+}
+
+// searchMatch is a matching document along with its words and which of
+// them matched.
+type searchMatch struct {
+    doc     *model.MessageDocument
+    words   []word
+    matched map[int]bool
+}
+
+// match reports whether doc matches, marking the words that made it match.
+func (m *matcher) match(doc *model.MessageDocument) (searchMatch, bool) {
+    match := searchMatch{
+        doc:     doc,
+        words:   tokenize(doc.Body),
+        matched: make(map[int]bool),
+    }
+
+    switch m.mode {
+    case model.SearchModePhrase, model.SearchModePrefix:
+        if len(m.terms) == 0 {
+            break
+        }
+        for i := 0; i+len(m.terms) <= len(match.words); i++ {
+            if m.phraseAt(match.words, i) {
+                for j := range m.terms {
+                    match.matched[i+j] = true
+                }
+            }
+        }
+    case model.SearchModeWildcard:
+        for i, w := range match.words {
+            if m.pattern.MatchString(w.text) {
+                match.matched[i] = true
+            }
+        }
+    default:
+        for i, w := range match.words {
+            for _, term := range m.terms {
+                if w.text == term {
+                    match.matched[i] = true
+                }
+            }
+        }
+    }
+
+    return match, len(match.matched) > 0
+}
+
+// phraseAt reports whether the terms appear in order starting at words[i],
+// the last one only as a prefix in prefix mode.
+func (m *matcher) phraseAt(words []word, i int) bool {
+    last := len(m.terms) - 1
+    for j, term := range m.terms {
+        text := words[i+j].text
+        if j == last && m.mode == model.SearchModePrefix {
+            if !strings.HasPrefix(text, term) {
+                return false
+            }
+        } else if text != term {
+            return false
+        }
+    }
+    return true
+}
+
+// highlight returns the body with every matched word wrapped in <em> tags.
+func (m searchMatch) highlight() string {
+    var b strings.Builder
+    body := m.doc.Body
+    last := 0
+    for i, w := range m.words {
+        if !m.matched[i] {
+            continue
+        }
+        b.WriteString(body[last:w.start])
+        b.WriteString("<em>")
+        b.WriteString(body[w.start:w.end])
+        b.WriteString("</em>")
+        last = w.end
+    }
+    b.WriteString(body[last:])
+    return b.String()
+}
+
+// sortMatches orders matches like the Elasticsearch sort: by creation time
+// with the id as tie-breaker, relevance being the number of matched words.
+func sortMatches(matches []searchMatch, order model.SearchSort) {
+    sort.Slice(matches, func(i, j int) bool {
+        a, b := matches[i], matches[j]
+        if order == model.SearchSortRelevance && len(a.matched) != len(b.matched) {
+            return len(a.matched) > len(b.matched)
+        }
+        if !a.doc.CreatedAt.Equal(b.doc.CreatedAt) {
+            if order == model.SearchSortOldest {
+                return a.doc.CreatedAt.Before(b.doc.CreatedAt)
+            }
+            return a.doc.CreatedAt.After(b.doc.CreatedAt)
+        }
+        if order == model.SearchSortOldest {
+            return a.doc.ID < b.doc.ID
+        }
+        return a.doc.ID > b.doc.ID
+    })
+}
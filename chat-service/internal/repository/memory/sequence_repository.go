@@ -0,0 +1,154 @@
+package memory
+
+import (
+    "context"
+    "fmt"
+    "sync"
+
+    "chat-service/internal/repository/redis"
+)
+
+// SequenceRepository allocates chat and message numbers like the Redis
+// counters: a missing counter is seeded from source, and numbers handed out
+// are never handed out again unless a counter is set back explicitly.
+type SequenceRepository struct {
+    mu       sync.Mutex
+    source   redis.SequenceSource
+    counters map[string]int
+}
+
+func NewSequenceRepository(source redis.SequenceSource) *SequenceRepository {
+    return &SequenceRepository{
+        source:   source,
+        counters: make(map[string]int),
+    }
+}
+
+func (r *SequenceRepository) NextChatNumber(ctx context.Context, applicationID string) (int, error) {
+    return r.reserve(ctx, chatSequenceKey(applicationID), 1, func(ctx context.Context) (int, error) {
+        return r.source.MaxChatNumber(ctx, applicationID)
+    })
+}
+
+func (r *SequenceRepository) NextMessageNumber(ctx context.Context, chatID uint64) (int, error) {
+    return r.reserve(ctx, messageSequenceKey(chatID), 1, func(ctx context.Context) (int, error) {
+        return r.source.MaxMessageNumber(ctx, chatID)
+    })
+}
+
+// ReserveMessageNumbers allocates count consecutive message numbers and
+// returns the first of them.
+func (r *SequenceRepository) ReserveMessageNumbers(ctx context.Context, chatID uint64, count int) (int, error) {
+    last, err := r.reserve(ctx, messageSequenceKey(chatID), count, func(ctx context.Context) (int, error) {
+        return r.source.MaxMessageNumber(ctx, chatID)
+    })
+    if err != nil {
+        return 0, err
+    }
+    return last - count + 1, nil
+}
+
+// ResyncChatNumber raises the chat counter of an application to the highest
+// chat number stored.
+func (r *SequenceRepository) ResyncChatNumber(ctx context.Context, applicationID string) error {
+    max, err := r.source.MaxChatNumber(ctx, applicationID)
+    if err != nil {
+        return err
+    }
+    return r.RaiseChatSequence(ctx, applicationID, max)
+}
+
+// ResyncMessageNumber is the message counterpart of ResyncChatNumber.
+func (r *SequenceRepository) ResyncMessageNumber(ctx context.Context, chatID uint64) error {
+    max, err := r.source.MaxMessageNumber(ctx, chatID)
+    if err != nil {
+        return err
+    }
+    return r.RaiseMessageSequence(ctx, chatID, max)
+}
+
+func (r *SequenceRepository) RaiseChatSequence(ctx context.Context, applicationID string, floor int) error {
+    r.raise(chatSequenceKey(applicationID), floor)
+    return nil
+}
+
+func (r *SequenceRepository) RaiseMessageSequence(ctx context.Context, chatID uint64, floor int) error {
+    r.raise(messageSequenceKey(chatID), floor)
+    return nil
+}
+
+// SetChatSequence overwrites the counter unconditionally, e.g. to simulate
+// a counter restored from an old snapshot.
+func (r *SequenceRepository) SetChatSequence(ctx context.Context, applicationID string, value int) error {
+    r.set(chatSequenceKey(applicationID), value)
+    return nil
+}
+
+// SetMessageSequence overwrites the counter unconditionally.
+func (r *SequenceRepository) SetMessageSequence(ctx context.Context, chatID uint64, value int) error {
+    r.set(messageSequenceKey(chatID), value)
+    return nil
+}
+
+// reserve advances the counter at key by count, seeding it first if it is
+// missing, and returns the new value.
+func (r *SequenceRepository) reserve(ctx context.Context, key string, count int, seed func(ctx context.Context) (int, error)) (int, error) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    value, ok := r.counters[key]
+    if !ok {
+        max, err := seed(ctx)
+        if err != nil {
+            return 0, fmt.Errorf("failed to seed sequence %s: %w", key, err)
+        }
+        value = max
+    }
+
+    value += count
+    r.counters[key] = value
+    return value, nil
+}
+
+func (r *SequenceRepository) raise(key string, floor int) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    if r.counters[key] < floor {
+        r.counters[key] = floor
+    }
+}
+
+func (r *SequenceRepository) set(key string, value int) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    r.counters[key] = value
+}
+
+func chatSequenceKey(applicationID string) string {
+    return fmt.Sprintf("app:%s:chat_seq", applicationID)
+}
+
+func messageSequenceKey(chatID uint64) string {
+    return fmt.Sprintf("chat:%d:msg_seq", chatID)
+}
+
+// SequenceSource reads the highest chat and message numbers from the
+// memory repositories, as mysql.SequenceSource does from MySQL.
+type SequenceSource struct {
+    chats    *ChatRepository
+    messages *MessageRepository
+}
+
+func NewSequenceSource(chats *ChatRepository, messages *MessageRepository) *SequenceSource {
+    return &SequenceSource{chats: chats, messages: messages}
+}
+
+func (s *SequenceSource) MaxChatNumber(ctx context.Context, applicationToken string) (int, error) {
+    return s.chats.MaxChatNumber(ctx, applicationToken)
+}
+
+func (s *SequenceSource) MaxMessageNumber(ctx context.Context, chatID uint64) (int, error) {
+    return s.messages.MaxMessageNumber(ctx, chatID)
+}
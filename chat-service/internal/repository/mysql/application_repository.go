@@ -0,0 +1,36 @@
+package mysql
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+)
+
+type ApplicationRepository struct {
+    db *sql.DB
+}
+
+func NewApplicationRepository(db *sql.DB) *ApplicationRepository {
+    return &ApplicationRepository{db: db}
+}
+
+// ExistsByToken reports whether an application with the given token exists,
+// using the unique_token index.
+func (r *ApplicationRepository) ExistsByToken(ctx context.Context, token string) (bool, error) {
+    query := `
+        SELECT 1
+        FROM applications
+        WHERE token = ?
+    `
+
+    var exists int
+    err := r.db.QueryRowContext(ctx, query, token).Scan(&exists)
+    if err == sql.ErrNoRows {
+        return false, nil
+    }
+    if err != nil {
+        return false, fmt.Errorf("failed to query application: %w", classify(err))
+    }
+
+    return true, nil
+}
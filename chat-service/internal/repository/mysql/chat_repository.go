@@ -22,22 +22,37 @@ func (r *ChatRepository) Create(ctx context.Context, chat *model.Chat) error {
     `
     fmt.Printf("Creating chat with application_id: %s, number: %d, messages_count: %d, created_at: %s\n", 
     chat.ApplicationID, chat.Number, chat.MessagesCount, chat.CreatedAt) 
-    result, err := r.db.ExecContext(ctx, query,
+    tx, err := r.db.BeginTx(ctx, nil)
+    if err != nil {
+        return fmt.Errorf("failed to begin transaction: %w", classify(err))
+    }
+    defer tx.Rollback()
+
+    result, err := tx.ExecContext(ctx, query,
         chat.ApplicationID,
         chat.Number,
         chat.MessagesCount,
         chat.CreatedAt,
     )
     if err != nil {
-        return fmt.Errorf("failed to insert chat: %w", err)
+        return fmt.Errorf("failed to insert chat: %w", classify(err))
     }
 
     id, err := result.LastInsertId()
     if err != nil {
-        return fmt.Errorf("failed to get last insert id: %w", err)
+        return fmt.Errorf("failed to get last insert id: %w", classify(err))
     }
 
     chat.ID = uint64(id)
+
+    if err := insertOutboxEvent(ctx, tx, model.EventChatCreated, chat.ApplicationID, model.ChatEventData(chat)); err != nil {
+        return err
+    }
+
+    if err := tx.Commit(); err != nil {
+        return fmt.Errorf("failed to commit chat: %w", classifyCommit(err))
+    }
+
     return nil
 }
 
@@ -61,25 +76,32 @@ func (r *ChatRepository) GetByNumber(ctx context.Context, applicationID string,
         return nil, nil
     }
     if err != nil {
-        return nil, fmt.Errorf("failed to query chat: %w", err)
+        return nil, fmt.Errorf("failed to query chat: %w", classify(err))
     }
     
     return chat, nil
 }
 
 
-func (r *ChatRepository) ListByApplication(ctx context.Context, applicationToken string) ([]*model.Chat, error) {
+// ListByApplication returns one keyset page of an application's chats
+// ordered by number, served by the unique_app_number index.
+func (r *ChatRepository) ListByApplication(ctx context.Context, applicationToken string, page model.PageQuery) ([]*model.Chat, bool, error) {
+    where, args, order, limit, reverse := keyset("number", page)
+
     query := `
-        SELECT c.id, c.application_id, c.number, c.messages_count, c.created_at
-        FROM chats c
-        JOIN applications a ON c.application_id = a.id
-        WHERE a.token = ?
-        ORDER BY c.number ASC
+        SELECT id, application_id, number, messages_count, created_at
+        FROM chats
+        WHERE application_id = ?` + where + `
+        ORDER BY number ` + order + `
+        LIMIT ?
     `
+
+    args = append([]interface{}{applicationToken}, args...)
+    args = append(args, limit)
     
-    rows, err := r.db.QueryContext(ctx, query, applicationToken)
+    rows, err := r.db.QueryContext(ctx, query, args...)
     if err != nil {
-        return nil, fmt.Errorf("failed to query chats: %w", err)
+        return nil, false, fmt.Errorf("failed to query chats: %w", classify(err))
     }
     defer rows.Close()
 
@@ -94,14 +116,24 @@ func (r *ChatRepository) ListByApplication(ctx context.Context, applicationToken
             &chat.CreatedAt,
         )
         if err != nil {
-            return nil, fmt.Errorf("failed to scan chat: %w", err)
+            return nil, false, fmt.Errorf("failed to scan chat: %w", classify(err))
         }
         chats = append(chats, chat)
     }
     
     if err := rows.Err(); err != nil {
-        return nil, fmt.Errorf("error iterating chats: %w", err)
+        return nil, false, fmt.Errorf("error iterating chats: %w", classify(err))
+    }
+
+    hasMore := len(chats) > page.Limit
+    if hasMore {
+        chats = chats[:page.Limit]
+    }
+    if reverse {
+        for i, j := 0, len(chats)-1; i < j; i, j = i+1, j-1 {
+            chats[i], chats[j] = chats[j], chats[i]
+        }
     }
     
-    return chats, nil
+    return chats, hasMore, nil
 }
@@ -0,0 +1,114 @@
+package mysql
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "sort"
+    "time"
+
+    "chat-service/internal/model"
+)
+
+type CounterRepository struct {
+    db *sql.DB
+}
+
+func NewCounterRepository(db *sql.DB) *CounterRepository {
+    return &CounterRepository{db: db}
+}
+
+// ApplyIncrements records every event key in processed_events and applies
+// its delta to chats_count / messages_count once per key that was not seen
+// before, so a redelivered batch leaves the counters untouched. It returns
+// the number of events that were new.
+func (r *CounterRepository) ApplyIncrements(ctx context.Context, events []model.CounterEvent) (int, error) {
+    tx, err := r.db.BeginTx(ctx, nil)
+    if err != nil {
+        return 0, fmt.Errorf("failed to begin transaction: %w", classify(err))
+    }
+    defer tx.Rollback()
+
+    chatsByApplication := make(map[string]int)
+    messagesByChat := make(map[uint64]int)
+    applied := 0
+
+    now := time.Now().UTC()
+    for _, event := range events {
+        result, err := tx.ExecContext(ctx,
+            `INSERT IGNORE INTO processed_events (event_key, processed_at) VALUES (?, ?)`,
+            event.Key, now)
+        if err != nil {
+            return 0, fmt.Errorf("failed to record processed event: %w", classify(err))
+        }
+
+        affected, err := result.RowsAffected()
+        if err != nil {
+            return 0, fmt.Errorf("failed to get affected rows: %w", classify(err))
+        }
+        if affected == 0 {
+            continue
+        }
+
+        applied++
+        if event.ApplicationToken != "" {
+            chatsByApplication[event.ApplicationToken] += event.Delta
+        }
+        if event.ChatID != 0 {
+            messagesByChat[event.ChatID] += event.Delta
+        }
+    }
+
+    // Rows are updated in key order so concurrent workers lock them in the
+    // same sequence and cannot deadlock each other.
+    tokens := make([]string, 0, len(chatsByApplication))
+    for token, delta := range chatsByApplication {
+        if delta == 0 {
+            continue
+        }
+        tokens = append(tokens, token)
+    }
+    sort.Strings(tokens)
+
+    for _, token := range tokens {
+        if _, err := tx.ExecContext(ctx,
+            `UPDATE applications SET chats_count = chats_count + ? WHERE token = ?`,
+            chatsByApplication[token], token); err != nil {
+            return 0, fmt.Errorf("failed to update chats_count: %w", classify(err))
+        }
+    }
+
+    chatIDs := make([]uint64, 0, len(messagesByChat))
+    for chatID, delta := range messagesByChat {
+        if delta == 0 {
+            continue
+        }
+        chatIDs = append(chatIDs, chatID)
+    }
+    sort.Slice(chatIDs, func(i, j int) bool { return chatIDs[i] < chatIDs[j] })
+
+    for _, chatID := range chatIDs {
+        if _, err := tx.ExecContext(ctx,
+            `UPDATE chats SET messages_count = messages_count + ? WHERE id = ?`,
+            messagesByChat[chatID], chatID); err != nil {
+            return 0, fmt.Errorf("failed to update messages_count: %w", classify(err))
+        }
+    }
+
+    if err := tx.Commit(); err != nil {
+        return 0, fmt.Errorf("failed to commit counters: %w", classify(err))
+    }
+
+    return applied, nil
+}
+
+// DeleteProcessedBefore prunes dedup keys older than the cutoff.
+func (r *CounterRepository) DeleteProcessedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+    result, err := r.db.ExecContext(ctx,
+        `DELETE FROM processed_events WHERE processed_at < ?`, cutoff)
+    if err != nil {
+        return 0, fmt.Errorf("failed to delete processed events: %w", classify(err))
+    }
+
+    return result.RowsAffected()
+}
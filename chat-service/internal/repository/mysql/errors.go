@@ -0,0 +1,46 @@
+package mysql
+
+import (
+    "context"
+    "database/sql/driver"
+    "errors"
+    "fmt"
+    "net"
+
+    mysqldriver "github.com/go-sql-driver/mysql"
+
+    "chat-service/internal/repository"
+    "chat-service/pkg/elasticsearch"
+)
+
+const mysqlErrDuplicateEntry = 1062
+
+// classify tags err with repository.ErrDuplicate or repository.ErrUnavailable
+// when it stems from a unique key violation or an unreachable backend.
+func classify(err error) error {
+    if err == nil {
+        return nil
+    }
+
+    var mysqlErr *mysqldriver.MySQLError
+    if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry {
+        return fmt.Errorf("%w: %w", repository.ErrDuplicate, err)
+    }
+
+    var netErr net.Error
+    if errors.Is(err, driver.ErrBadConn) ||
+        errors.Is(err, mysqldriver.ErrInvalidConn) ||
+        errors.Is(err, context.DeadlineExceeded) ||
+        errors.Is(err, elasticsearch.ErrUnavailable) ||
+        errors.As(err, &netErr) {
+        return fmt.Errorf("%w: %w", repository.ErrUnavailable, err)
+    }
+
+    return err
+}
+
+// classifyCommit classifies a failed commit, which also leaves it unknown
+// whether the transaction was applied.
+func classifyCommit(err error) error {
+    return fmt.Errorf("%w: %w", repository.ErrCommitUnknown, classify(err))
+}
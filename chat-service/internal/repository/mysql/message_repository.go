@@ -5,20 +5,27 @@ import (
     "database/sql" 
     "encoding/json"
     "fmt"
+    "strings"
+    "time"
     
     "chat-service/internal/model"
     "chat-service/pkg/elasticsearch"
 )
 
+// MessageRepository stores messages in MySQL and keeps the search index in
+// step through indexer, which sends documents to Elasticsearch in the
+// background once the MySQL transaction has committed.
 type MessageRepository struct {
-    db *sql.DB
-    es *elasticsearch.Client
+    db      *sql.DB
+    es      *elasticsearch.Client
+    indexer *elasticsearch.BulkIndexer
 }
 
-func NewMessageRepository(db *sql.DB, es *elasticsearch.Client) *MessageRepository {
+func NewMessageRepository(db *sql.DB, es *elasticsearch.Client, indexer *elasticsearch.BulkIndexer) *MessageRepository {
     return &MessageRepository{
-        db: db,
-        es: es,
+        db:      db,
+        es:      es,
+        indexer: indexer,
     }
 }
 
@@ -36,58 +43,294 @@ func NewMessageRepository(db *sql.DB, es *elasticsearch.Client) *MessageReposito
 // @Failure     404 {object} model.ErrorResponse
 // @Failure     500 {object} model.ErrorResponse
 // @Router      /applications/{token}/chats/{number}/messages [post]
-func (r *MessageRepository) Create(ctx context.Context, message *model.Message) error {
+func (r *MessageRepository) Create(ctx context.Context, chat *model.Chat, message *model.Message) error {
     query := `
         INSERT INTO messages (chat_id, number, body, created_at)
         VALUES (?, ?, ?, ?)
     `
     
-    result, err := r.db.ExecContext(ctx, query,
+    tx, err := r.db.BeginTx(ctx, nil)
+    if err != nil {
+        return fmt.Errorf("failed to begin transaction: %w", classify(err))
+    }
+    defer tx.Rollback()
+
+    result, err := tx.ExecContext(ctx, query,
         message.ChatID,
         message.Number,
         message.Body,
         message.CreatedAt,
     )
     if err != nil {
-        return fmt.Errorf("failed to insert message: %w", err)
+        return fmt.Errorf("failed to insert message: %w", classify(err))
     }
 
     id, err := result.LastInsertId()
     if err != nil {
-        return fmt.Errorf("failed to get last insert id: %w", err)
+        return fmt.Errorf("failed to get last insert id: %w", classify(err))
     }
 
     message.ID = uint64(id)
-    
-    if err := r.es.Index("messages", fmt.Sprintf("%d", message.ID), message); err != nil {
-        return fmt.Errorf("failed to index message: %w", err)
+
+    if err := insertOutboxEvent(ctx, tx, model.EventMessageCreated, chat.ApplicationID, model.MessageEventData(message)); err != nil {
+        return err
+    }
+
+    if err := tx.Commit(); err != nil {
+        return fmt.Errorf("failed to commit message: %w", classifyCommit(err))
     }
     
+    r.queueIndex(ctx, chat, message)
+    
     return nil
 }
 
 
-// @Summary     List messages
-// @Description Retrieves all messages from a specific chat
-// @Tags        messages
-// @Produce     json
-// @Param       token  path string true "Application Token"
-// @Param       number path int    true "Chat Number"
-// @Success     200 {array} model.MessageResponse
-// @Failure     404 {object} model.ErrorResponse
-// @Failure     500 {object} model.ErrorResponse
-// @Router      /applications/{token}/chats/{number}/messages [get]
-func (r *MessageRepository) ListByChat(ctx context.Context, chatID uint64) ([]*model.Message, error) {
+// CreateBatch inserts messages of one chat with a single multi-row INSERT,
+// records a message_created event for each and queues them for indexing. IDs are
+// read back by number because InnoDB does not promise consecutive
+// auto-increment values for one statement.
+func (r *MessageRepository) CreateBatch(ctx context.Context, chat *model.Chat, messages []*model.Message) error {
+    if len(messages) == 0 {
+        return nil
+    }
+
+    tx, err := r.db.BeginTx(ctx, nil)
+    if err != nil {
+        return fmt.Errorf("failed to begin transaction: %w", classify(err))
+    }
+    defer tx.Rollback()
+
+    placeholders := make([]string, len(messages))
+    args := make([]interface{}, 0, len(messages)*4)
+    byNumber := make(map[int]*model.Message, len(messages))
+    minNumber, maxNumber := messages[0].Number, messages[0].Number
+    for i, message := range messages {
+        placeholders[i] = "(?, ?, ?, ?)"
+        args = append(args, chat.ID, message.Number, message.Body, message.CreatedAt)
+        byNumber[message.Number] = message
+        if message.Number < minNumber {
+            minNumber = message.Number
+        }
+        if message.Number > maxNumber {
+            maxNumber = message.Number
+        }
+    }
+
+    query := `
+        INSERT INTO messages (chat_id, number, body, created_at)
+        VALUES ` + strings.Join(placeholders, ", ")
+
+    if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+        return fmt.Errorf("failed to insert messages: %w", classify(err))
+    }
+
+    rows, err := tx.QueryContext(ctx, `
+        SELECT id, number
+        FROM messages
+        WHERE chat_id = ? AND number BETWEEN ? AND ?
+    `, chat.ID, minNumber, maxNumber)
+    if err != nil {
+        return fmt.Errorf("failed to query inserted messages: %w", classify(err))
+    }
+    for rows.Next() {
+        var id uint64
+        var number int
+        if err := rows.Scan(&id, &number); err != nil {
+            rows.Close()
+            return fmt.Errorf("failed to scan inserted message: %w", err)
+        }
+        if message, ok := byNumber[number]; ok {
+            message.ID = id
+        }
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return fmt.Errorf("error iterating inserted messages: %w", classify(err))
+    }
+
+    events := make([]interface{}, len(messages))
+    for i, message := range messages {
+        message.ChatID = chat.ID
+        events[i] = model.MessageEventData(message)
+    }
+
+    if err := insertOutboxEvents(ctx, tx, model.EventMessageCreated, chat.ApplicationID, events); err != nil {
+        return err
+    }
+
+    if err := tx.Commit(); err != nil {
+        return fmt.Errorf("failed to commit messages: %w", classifyCommit(err))
+    }
+
+    for _, message := range messages {
+        r.queueIndex(ctx, chat, message)
+    }
+
+    return nil
+}
+
+// GetByNumber looks a message up by (chatID, number) through the
+// unique_chat_number index. It returns nil when no such message exists.
+func (r *MessageRepository) GetByNumber(ctx context.Context, chatID uint64, number int) (*model.Message, error) {
     query := `
         SELECT id, chat_id, number, body, created_at
         FROM messages
-        WHERE chat_id = ?
-        ORDER BY number ASC
+        WHERE chat_id = ? AND number = ?
     `
-    
-    rows, err := r.db.QueryContext(ctx, query, chatID)
+
+    message := &model.Message{}
+    err := r.db.QueryRowContext(ctx, query, chatID, number).Scan(
+        &message.ID,
+        &message.ChatID,
+        &message.Number,
+        &message.Body,
+        &message.CreatedAt,
+    )
+
+    if err == sql.ErrNoRows {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, fmt.Errorf("failed to query message: %w", classify(err))
+    }
+
+    return message, nil
+}
+
+// Update replaces the body of the message identified by (chat, number),
+// records a message_updated event and re-indexes the document. It returns
+// nil when no such message exists.
+func (r *MessageRepository) Update(ctx context.Context, chat *model.Chat, number int, body string) (*model.Message, error) {
+    tx, err := r.db.BeginTx(ctx, nil)
     if err != nil {
-        return nil, fmt.Errorf("failed to query messages: %w", err)
+        return nil, fmt.Errorf("failed to begin transaction: %w", classify(err))
+    }
+    defer tx.Rollback()
+
+    message, err := lockMessage(ctx, tx, chat.ID, number)
+    if err != nil || message == nil {
+        return nil, err
+    }
+
+    if _, err := tx.ExecContext(ctx,
+        `UPDATE messages SET body = ? WHERE id = ?`,
+        body, message.ID); err != nil {
+        return nil, fmt.Errorf("failed to update message: %w", classify(err))
+    }
+    message.Body = body
+
+    if err := insertOutboxEvent(ctx, tx, model.EventMessageUpdated, chat.ApplicationID, model.MessageEventData(message)); err != nil {
+        return nil, err
+    }
+
+    if err := tx.Commit(); err != nil {
+        return nil, fmt.Errorf("failed to commit message: %w", classifyCommit(err))
+    }
+
+    r.queueIndex(ctx, chat, message)
+
+    return message, nil
+}
+
+// Delete removes the message identified by (chat, number), records a
+// message_deleted event and drops the document from the index. It returns
+// nil when no such message exists.
+func (r *MessageRepository) Delete(ctx context.Context, chat *model.Chat, number int) (*model.Message, error) {
+    tx, err := r.db.BeginTx(ctx, nil)
+    if err != nil {
+        return nil, fmt.Errorf("failed to begin transaction: %w", classify(err))
+    }
+    defer tx.Rollback()
+
+    message, err := lockMessage(ctx, tx, chat.ID, number)
+    if err != nil || message == nil {
+        return nil, err
+    }
+
+    if _, err := tx.ExecContext(ctx,
+        `DELETE FROM messages WHERE id = ?`,
+        message.ID); err != nil {
+        return nil, fmt.Errorf("failed to delete message: %w", classify(err))
+    }
+
+    if err := insertOutboxEvent(ctx, tx, model.EventMessageDeleted, chat.ApplicationID, model.MessageEventData(message)); err != nil {
+        return nil, err
+    }
+
+    if err := tx.Commit(); err != nil {
+        return nil, fmt.Errorf("failed to commit message deletion: %w", classifyCommit(err))
+    }
+
+    r.queueRemoval(ctx, message)
+
+    return message, nil
+}
+
+// queueIndex queues a committed message for indexing. The write already
+// succeeded, so a full queue or a closed indexer is only reported: failing
+// the request would make clients retry a stored message under a new number.
+// indexcheck finds and repairs the missing document.
+func (r *MessageRepository) queueIndex(ctx context.Context, chat *model.Chat, message *model.Message) {
+    if err := r.indexer.Index(ctx, elasticsearch.MessagesAlias, fmt.Sprintf("%d", message.ID), model.NewMessageDocument(chat, message)); err != nil {
+        fmt.Printf("Failed to queue message %d for indexing: %v\n", message.ID, err)
+    }
+}
+
+// queueRemoval is the deletion counterpart of queueIndex.
+func (r *MessageRepository) queueRemoval(ctx context.Context, message *model.Message) {
+    if err := r.indexer.Delete(ctx, elasticsearch.MessagesAlias, fmt.Sprintf("%d", message.ID)); err != nil {
+        fmt.Printf("Failed to queue message %d for removal from index: %v\n", message.ID, err)
+    }
+}
+
+func lockMessage(ctx context.Context, tx *sql.Tx, chatID uint64, number int) (*model.Message, error) {
+    query := `
+        SELECT id, chat_id, number, body, created_at
+        FROM messages
+        WHERE chat_id = ? AND number = ?
+        FOR UPDATE
+    `
+
+    message := &model.Message{}
+    err := tx.QueryRowContext(ctx, query, chatID, number).Scan(
+        &message.ID,
+        &message.ChatID,
+        &message.Number,
+        &message.Body,
+        &message.CreatedAt,
+    )
+
+    if err == sql.ErrNoRows {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, fmt.Errorf("failed to query message: %w", classify(err))
+    }
+
+    return message, nil
+}
+
+// ListByChat returns one keyset page of a chat's messages ordered by number,
+// served by the unique_chat_number index. hasMore reports whether another
+// page exists in the direction the page was fetched.
+func (r *MessageRepository) ListByChat(ctx context.Context, chatID uint64, page model.PageQuery) ([]*model.Message, bool, error) {
+    where, args, order, limit, reverse := keyset("number", page)
+
+    query := `
+        SELECT id, chat_id, number, body, created_at
+        FROM messages
+        WHERE chat_id = ?` + where + `
+        ORDER BY number ` + order + `
+        LIMIT ?
+    `
+
+    args = append([]interface{}{chatID}, args...)
+    args = append(args, limit)
+
+    rows, err := r.db.QueryContext(ctx, query, args...)
+    if err != nil {
+        return nil, false, fmt.Errorf("failed to query messages: %w", classify(err))
     }
     defer rows.Close()
 
@@ -102,42 +345,261 @@ func (r *MessageRepository) ListByChat(ctx context.Context, chatID uint64) ([]*m
             &msg.CreatedAt,
         )
         if err != nil {
-            return nil, fmt.Errorf("failed to scan message: %w", err)
+            return nil, false, fmt.Errorf("failed to scan message: %w", classify(err))
         }
         messages = append(messages, msg)
     }
     
     if err := rows.Err(); err != nil {
-        return nil, fmt.Errorf("error iterating messages: %w", err)
+        return nil, false, fmt.Errorf("error iterating messages: %w", classify(err))
+    }
+
+    hasMore := len(messages) > page.Limit
+    if hasMore {
+        messages = messages[:page.Limit]
+    }
+    if reverse {
+        for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+            messages[i], messages[j] = messages[j], messages[i]
+        }
     }
     
-    return messages, nil
+    return messages, hasMore, nil
 }
 
+// ListAfterID returns up to limit messages with an id greater than afterID,
+// in id order, as search index documents. It walks the whole table by
+// primary key without OFFSET, so repeated calls stream every message
+// exactly once.
+func (r *MessageRepository) ListAfterID(ctx context.Context, afterID uint64, limit int) ([]*model.MessageDocument, error) {
+    query := `
+        SELECT m.id, m.chat_id, m.number, m.body, m.created_at, c.application_id, c.number
+        FROM messages m
+        JOIN chats c ON c.id = m.chat_id
+        WHERE m.id > ?
+        ORDER BY m.id
+        LIMIT ?
+    `
 
-// @Summary     Search messages
-// @Description Searches for messages within a specific chat
-// @Tags        messages
-// @Produce     json
-// @Param       token  path  string true "Application Token"
-// @Param       number path  int    true "Chat Number"
-// @Param       instabug      query string true "Search Query"
-// @Success     200 {array} model.MessageResponse
-// @Failure     400 {object} model.ErrorResponse
-// @Failure     404 {object} model.ErrorResponse
-// @Failure     500 {object} model.ErrorResponse
-// @Router      /applications/{token}/chats/{number}/messages/search [get]
+    rows, err := r.db.QueryContext(ctx, query, afterID, limit)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query messages: %w", classify(err))
+    }
+    defer rows.Close()
+
+    var documents []*model.MessageDocument
+    for rows.Next() {
+        doc := &model.MessageDocument{}
+        err := rows.Scan(
+            &doc.ID,
+            &doc.ChatID,
+            &doc.Number,
+            &doc.Body,
+            &doc.CreatedAt,
+            &doc.ApplicationToken,
+            &doc.ChatNumber,
+        )
+        if err != nil {
+            return nil, fmt.Errorf("failed to scan message: %w", classify(err))
+        }
+        documents = append(documents, doc)
+    }
+
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("error iterating messages: %w", classify(err))
+    }
+
+    return documents, nil
+}
+
+// EachChatStats streams, in chat_id order, how many messages every chat has
+// along with the highest and the sum of their numbers. Comparing these with
+// the search index cheaply tells which chats need a closer look.
+func (r *MessageRepository) EachChatStats(ctx context.Context, fn func(chatID uint64, count int64, maxNumber int, numberSum int64) error) error {
+    rows, err := r.db.QueryContext(ctx, `
+        SELECT chat_id, COUNT(*), MAX(number), SUM(number)
+        FROM messages
+        GROUP BY chat_id
+        ORDER BY chat_id
+    `)
+    if err != nil {
+        return fmt.Errorf("failed to query chat stats: %w", classify(err))
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        var chatID uint64
+        var count, sum int64
+        var max int
+        if err := rows.Scan(&chatID, &count, &max, &sum); err != nil {
+            return fmt.Errorf("failed to scan chat stats: %w", err)
+        }
+        if err := fn(chatID, count, max, sum); err != nil {
+            return err
+        }
+    }
+
+    if err := rows.Err(); err != nil {
+        return fmt.Errorf("error iterating chat stats: %w", classify(err))
+    }
+    return nil
+}
 
-func (r *MessageRepository) Search(ctx context.Context, query map[string]interface{}) ([]*model.Message, error) {
-    searchResults, err := r.es.Search("messages", query)
+// IDsByChat returns the ids of every message of a chat in ascending order.
+func (r *MessageRepository) IDsByChat(ctx context.Context, chatID uint64) ([]uint64, error) {
+    rows, err := r.db.QueryContext(ctx,
+        `SELECT id FROM messages WHERE chat_id = ? ORDER BY id`,
+        chatID)
     if err != nil {
-        return nil, fmt.Errorf("failed to execute search: %w", err)
+        return nil, fmt.Errorf("failed to query message ids: %w", classify(err))
+    }
+    defer rows.Close()
+
+    var ids []uint64
+    for rows.Next() {
+        var id uint64
+        if err := rows.Scan(&id); err != nil {
+            return nil, fmt.Errorf("failed to scan message id: %w", err)
+        }
+        ids = append(ids, id)
+    }
+
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("error iterating message ids: %w", classify(err))
+    }
+    return ids, nil
+}
+
+// ListByIDs returns the messages with the given ids as search index
+// documents. Ids that no longer exist are skipped.
+func (r *MessageRepository) ListByIDs(ctx context.Context, ids []uint64) ([]*model.MessageDocument, error) {
+    if len(ids) == 0 {
+        return nil, nil
+    }
+
+    placeholders := make([]string, len(ids))
+    args := make([]interface{}, len(ids))
+    for i, id := range ids {
+        placeholders[i] = "?"
+        args[i] = id
+    }
+
+    rows, err := r.db.QueryContext(ctx, `
+        SELECT m.id, m.chat_id, m.number, m.body, m.created_at, c.application_id, c.number
+        FROM messages m
+        JOIN chats c ON c.id = m.chat_id
+        WHERE m.id IN (`+strings.Join(placeholders, ", ")+`)
+        ORDER BY m.id
+    `, args...)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query messages: %w", classify(err))
+    }
+    defer rows.Close()
+
+    var documents []*model.MessageDocument
+    for rows.Next() {
+        doc := &model.MessageDocument{}
+        err := rows.Scan(
+            &doc.ID,
+            &doc.ChatID,
+            &doc.Number,
+            &doc.Body,
+            &doc.CreatedAt,
+            &doc.ApplicationToken,
+            &doc.ChatNumber,
+        )
+        if err != nil {
+            return nil, fmt.Errorf("failed to scan message: %w", classify(err))
+        }
+        documents = append(documents, doc)
+    }
+
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("error iterating messages: %w", classify(err))
+    }
+    return documents, nil
+}
+
+// MaxID returns the highest message id, or 0 when there are no messages.
+func (r *MessageRepository) MaxID(ctx context.Context) (uint64, error) {
+    var max uint64
+    err := r.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(id), 0) FROM messages`).Scan(&max)
+    if err != nil {
+        return 0, fmt.Errorf("failed to query max message id: %w", classify(err))
+    }
+    return max, nil
+}
+
+
+// Search runs a full-text query on message bodies restricted by scope
+// against the Elasticsearch messages index and returns the requested page
+// together with the total hit count and any highlighted body fragments.
+func (r *MessageRepository) Search(ctx context.Context, scope model.SearchScope, search model.SearchQuery) (*model.SearchResult, error) {
+    filter := map[string]interface{}{
+        "term": map[string]interface{}{
+            "application_token": scope.ApplicationToken,
+        },
+    }
+    if scope.ChatID != 0 {
+        filter = map[string]interface{}{
+            "term": map[string]interface{}{
+                "chat_id": scope.ChatID,
+            },
+        }
+    }
+    filters := []map[string]interface{}{filter}
+
+    if search.CreatedAfter != nil || search.CreatedBefore != nil {
+        createdAt := map[string]interface{}{}
+        if search.CreatedAfter != nil {
+            createdAt["gte"] = search.CreatedAfter.UTC().Format(time.RFC3339Nano)
+        }
+        if search.CreatedBefore != nil {
+            createdAt["lte"] = search.CreatedBefore.UTC().Format(time.RFC3339Nano)
+        }
+        filters = append(filters, map[string]interface{}{
+            "range": map[string]interface{}{
+                "created_at": createdAt,
+            },
+        })
+    }
+
+    searchQuery := map[string]interface{}{
+        "from": (search.Page - 1) * search.Size,
+        "size": search.Size,
+        "query": map[string]interface{}{
+            "bool": map[string]interface{}{
+                "must":   []map[string]interface{}{bodyQuery(search)},
+                "filter": filters,
+            },
+        },
+        "sort": searchSort(search.Sort),
+        "highlight": map[string]interface{}{
+            "fields": map[string]interface{}{
+                "body": map[string]interface{}{
+                    "number_of_fragments": 3,
+                    "fragment_size":       150,
+                },
+            },
+        },
+    }
+
+
+    searchResults, err := r.es.Search(elasticsearch.MessagesAlias, searchQuery)
+    if err != nil {
+        return nil, fmt.Errorf("failed to execute search: %w", classify(err))
     }
 
     var searchResponse struct {
         Hits struct {
+            Total struct {
+                Value int64 `json:"value"`
+            } `json:"total"`
             Hits []struct {
-                Source *model.Message `json:"_source"`
+                Source    *model.MessageDocument `json:"_source"`
+                Highlight struct {
+                    Body []string `json:"body"`
+                } `json:"highlight"`
             } `json:"hits"`
         } `json:"hits"`
     }
@@ -146,10 +608,234 @@ func (r *MessageRepository) Search(ctx context.Context, query map[string]interfa
         return nil, fmt.Errorf("failed to parse search results: %w", err)
     }
 
-    messages := make([]*model.Message, len(searchResponse.Hits.Hits))
+    result := &model.SearchResult{
+        Total: searchResponse.Hits.Total.Value,
+        Hits:  make([]*model.SearchHit, len(searchResponse.Hits.Hits)),
+    }
     for i, hit := range searchResponse.Hits.Hits {
-        messages[i] = hit.Source
+        highlights := hit.Highlight.Body
+        if highlights == nil {
+            highlights = []string{}
+        }
+        result.Hits[i] = &model.SearchHit{
+            MessageDocument: hit.Source,
+            Highlights:      highlights,
+        }
+    }
+
+    return result, nil
+}
+
+// bodyQuery turns the already validated text and mode of a search into the
+// Elasticsearch query on the message body.
+func bodyQuery(search model.SearchQuery) map[string]interface{} {
+    switch search.Mode {
+    case model.SearchModePhrase:
+        return map[string]interface{}{
+            "match_phrase": map[string]interface{}{
+                "body": search.Text,
+            },
+        }
+    case model.SearchModePrefix:
+        return map[string]interface{}{
+            "match_phrase_prefix": map[string]interface{}{
+                "body": search.Text,
+            },
+        }
+    case model.SearchModeWildcard:
+        return map[string]interface{}{
+            "wildcard": map[string]interface{}{
+                "body": map[string]interface{}{
+                    "value":            search.Text,
+                    "case_insensitive": true,
+                },
+            },
+        }
+    case model.SearchModeQuery:
+        return map[string]interface{}{
+            "query_string": map[string]interface{}{
+                "query":                  search.Text,
+                "default_field":          "body",
+                "allow_leading_wildcard": false,
+            },
+        }
+    default:
+        match := map[string]interface{}{
+            "query": search.Text,
+        }
+        if search.Fuzziness != "" {
+            match["fuzziness"] = search.Fuzziness
+        }
+        return map[string]interface{}{
+            "match": map[string]interface{}{
+                "body": match,
+            },
+        }
+    }
+}
+
+func searchSort(order model.SearchSort) []map[string]interface{} {
+    switch order {
+    case model.SearchSortRelevance:
+        return []map[string]interface{}{
+            {"_score": map[string]interface{}{"order": "desc"}},
+            {"created_at": map[string]interface{}{"order": "desc"}},
+        }
+    case model.SearchSortOldest:
+        return []map[string]interface{}{
+            {"created_at": map[string]interface{}{"order": "asc"}},
+        }
+    default:
+        return []map[string]interface{}{
+            {"created_at": map[string]interface{}{"order": "desc"}},
+        }
+    }
+}
+
+// SearchFullText is the MySQL counterpart of Search, used while
+// Elasticsearch is unavailable. It matches bodies through the ft_body
+// FULLTEXT index and returns no highlights. Phrase, prefix and wildcard
+// modes map onto boolean mode; query strings and fuzziness have no MySQL
+// equivalent and fall back to natural language mode on the bare words.
+func (r *MessageRepository) SearchFullText(ctx context.Context, scope model.SearchScope, search model.SearchQuery) (*model.SearchResult, error) {
+    match := fullTextMatch(search)
+    where := match
+    args := []interface{}{fullTextTerms(search)}
+
+    if scope.ChatID != 0 {
+        where += ` AND m.chat_id = ?`
+        args = append(args, scope.ChatID)
+    } else {
+        where += ` AND c.application_id = ?`
+        args = append(args, scope.ApplicationToken)
+    }
+    if search.CreatedAfter != nil {
+        where += ` AND m.created_at >= ?`
+        args = append(args, search.CreatedAfter.UTC())
+    }
+    if search.CreatedBefore != nil {
+        where += ` AND m.created_at <= ?`
+        args = append(args, search.CreatedBefore.UTC())
+    }
+
+    result := &model.SearchResult{Hits: []*model.SearchHit{}}
+
+    err := r.db.QueryRowContext(ctx, `
+        SELECT COUNT(*)
+        FROM messages m
+        JOIN chats c ON c.id = m.chat_id
+        WHERE `+where, args...).Scan(&result.Total)
+    if err != nil {
+        return nil, fmt.Errorf("failed to count full-text matches: %w", classify(err))
+    }
+    if result.Total == 0 {
+        return result, nil
+    }
+
+    rows, err := r.db.QueryContext(ctx, `
+        SELECT m.id, m.chat_id, m.number, m.body, m.created_at, c.application_id, c.number
+        FROM messages m
+        JOIN chats c ON c.id = m.chat_id
+        WHERE `+where+`
+        ORDER BY `+fullTextOrder(search.Sort, match)+`
+        LIMIT ? OFFSET ?
+    `, fullTextPageArgs(search, args)...)
+    if err != nil {
+        return nil, fmt.Errorf("failed to run full-text search: %w", classify(err))
     }
+    defer rows.Close()
 
-    return messages, nil
-}
\ No newline at end of file
+    for rows.Next() {
+        doc := &model.MessageDocument{}
+        err := rows.Scan(
+            &doc.ID,
+            &doc.ChatID,
+            &doc.Number,
+            &doc.Body,
+            &doc.CreatedAt,
+            &doc.ApplicationToken,
+            &doc.ChatNumber,
+        )
+        if err != nil {
+            return nil, fmt.Errorf("failed to scan message: %w", classify(err))
+        }
+        result.Hits = append(result.Hits, &model.SearchHit{
+            MessageDocument: doc,
+            Highlights:      []string{},
+        })
+    }
+
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("error iterating full-text matches: %w", classify(err))
+    }
+
+    return result, nil
+}
+
+func fullTextMatch(search model.SearchQuery) string {
+    switch search.Mode {
+    case model.SearchModePhrase, model.SearchModePrefix, model.SearchModeWildcard:
+        return `MATCH(m.body) AGAINST (? IN BOOLEAN MODE)`
+    default:
+        return `MATCH(m.body) AGAINST (? IN NATURAL LANGUAGE MODE)`
+    }
+}
+
+// fullTextTerms rewrites the search text for fullTextMatch, dropping
+// characters that carry meaning in boolean mode.
+func fullTextTerms(search model.SearchQuery) string {
+    words := strings.FieldsFunc(search.Text, isFullTextOperator)
+
+    switch search.Mode {
+    case model.SearchModePhrase:
+        return `"` + strings.Join(words, " ") + `"`
+    case model.SearchModePrefix:
+        for i := range words {
+            words[i] = "+" + words[i]
+        }
+        if len(words) > 0 {
+            words[len(words)-1] += "*"
+        }
+        return strings.Join(words, " ")
+    case model.SearchModeWildcard:
+        // Boolean mode only knows trailing wildcards: keep the literal
+        // part before the first wildcard as a prefix.
+        prefix := search.Text
+        if i := strings.IndexAny(prefix, "*?"); i >= 0 {
+            prefix = prefix[:i]
+        }
+        prefix = strings.Join(strings.FieldsFunc(prefix, isFullTextOperator), "")
+        if prefix == "" {
+            return ""
+        }
+        return prefix + "*"
+    default:
+        return strings.Join(words, " ")
+    }
+}
+
+func isFullTextOperator(c rune) bool {
+    return strings.ContainsRune(" \t\n\"+-<>()~*?@:", c)
+}
+
+func fullTextOrder(sort model.SearchSort, match string) string {
+    switch sort {
+    case model.SearchSortRelevance:
+        return match + ` DESC, m.created_at DESC, m.id DESC`
+    case model.SearchSortOldest:
+        return `m.created_at ASC, m.id ASC`
+    default:
+        return `m.created_at DESC, m.id DESC`
+    }
+}
+
+// fullTextPageArgs returns the arguments of the page query: the filter
+// arguments, the search terms again when ordering by relevance, and the
+// limit and offset.
+func fullTextPageArgs(search model.SearchQuery, args []interface{}) []interface{} {
+    pageArgs := append([]interface{}{}, args...)
+    if search.Sort == model.SearchSortRelevance {
+        pageArgs = append(pageArgs, fullTextTerms(search))
+    }
+    return append(pageArgs, search.Size, (search.Page-1)*search.Size)
+}
@@ -0,0 +1,184 @@
+package mysql
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "fmt"
+    "strings"
+    "time"
+
+    "chat-service/internal/model"
+    "chat-service/pkg/events"
+)
+
+type OutboxRepository struct {
+    db *sql.DB
+}
+
+func NewOutboxRepository(db *sql.DB) *OutboxRepository {
+    return &OutboxRepository{db: db}
+}
+
+// insertOutboxEvent writes an event row inside the caller's transaction so
+// the event is committed (or rolled back) together with the data it describes.
+// The payload is the complete event envelope, so its id stays the same
+// however often the relay has to retry.
+func insertOutboxEvent(ctx context.Context, tx *sql.Tx, eventType string, applicationToken string, data interface{}) error {
+    now := time.Now().UTC()
+    payload, err := outboxPayload(eventType, applicationToken, now, data)
+    if err != nil {
+        return err
+    }
+
+    query := `
+        INSERT INTO outbox_events (event_type, payload, created_at, next_attempt_at)
+        VALUES (?, ?, ?, ?)
+    `
+
+    if _, err := tx.ExecContext(ctx, query, eventType, payload, now, now); err != nil {
+        return fmt.Errorf("failed to insert outbox event: %w", classify(err))
+    }
+
+    return nil
+}
+
+// insertOutboxEvents writes one event row per item with a single multi-row
+// INSERT inside the caller's transaction.
+func insertOutboxEvents(ctx context.Context, tx *sql.Tx, eventType string, applicationToken string, items []interface{}) error {
+    if len(items) == 0 {
+        return nil
+    }
+
+    now := time.Now().UTC()
+    placeholders := make([]string, len(items))
+    args := make([]interface{}, 0, len(items)*4)
+    for i, data := range items {
+        payload, err := outboxPayload(eventType, applicationToken, now, data)
+        if err != nil {
+            return err
+        }
+        placeholders[i] = "(?, ?, ?, ?)"
+        args = append(args, eventType, payload, now, now)
+    }
+
+    query := `
+        INSERT INTO outbox_events (event_type, payload, created_at, next_attempt_at)
+        VALUES ` + strings.Join(placeholders, ", ")
+
+    if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+        return fmt.Errorf("failed to insert outbox events: %w", classify(err))
+    }
+
+    return nil
+}
+
+func outboxPayload(eventType string, applicationToken string, occurredAt time.Time, data interface{}) ([]byte, error) {
+    envelope, err := events.New(eventType, applicationToken, occurredAt, data)
+    if err != nil {
+        return nil, err
+    }
+
+    payload, err := json.Marshal(envelope)
+    if err != nil {
+        return nil, fmt.Errorf("failed to marshal %s event: %w", eventType, err)
+    }
+    return payload, nil
+}
+
+// ProcessPending locks up to limit due events, hands each one to publish and
+// records the outcome, all in one transaction. SKIP LOCKED lets several relay
+// instances share the table without publishing the same row concurrently.
+func (r *OutboxRepository) ProcessPending(
+    ctx context.Context,
+    limit int,
+    publish func(event *model.OutboxEvent) error,
+    backoff func(attempts int) time.Duration,
+) (int, error) {
+    tx, err := r.db.BeginTx(ctx, nil)
+    if err != nil {
+        return 0, fmt.Errorf("failed to begin transaction: %w", classify(err))
+    }
+    defer tx.Rollback()
+
+    query := `
+        SELECT id, event_type, payload, attempts, created_at
+        FROM outbox_events
+        WHERE published_at IS NULL AND next_attempt_at <= ?
+        ORDER BY id ASC
+        LIMIT ?
+        FOR UPDATE SKIP LOCKED
+    `
+
+    rows, err := tx.QueryContext(ctx, query, time.Now().UTC(), limit)
+    if err != nil {
+        return 0, fmt.Errorf("failed to query outbox events: %w", classify(err))
+    }
+
+    var events []*model.OutboxEvent
+    for rows.Next() {
+        event := &model.OutboxEvent{}
+        err := rows.Scan(
+            &event.ID,
+            &event.EventType,
+            &event.Payload,
+            &event.Attempts,
+            &event.CreatedAt,
+        )
+        if err != nil {
+            rows.Close()
+            return 0, fmt.Errorf("failed to scan outbox event: %w", classify(err))
+        }
+        events = append(events, event)
+    }
+    rows.Close()
+
+    if err := rows.Err(); err != nil {
+        return 0, fmt.Errorf("error iterating outbox events: %w", classify(err))
+    }
+
+    published := 0
+    for _, event := range events {
+        now := time.Now().UTC()
+        if err := publish(event); err != nil {
+            _, err := tx.ExecContext(ctx, `
+                UPDATE outbox_events
+                SET attempts = attempts + 1, last_error = ?, next_attempt_at = ?
+                WHERE id = ?
+            `, err.Error(), now.Add(backoff(event.Attempts+1)), event.ID)
+            if err != nil {
+                return 0, fmt.Errorf("failed to record outbox failure: %w", classify(err))
+            }
+            continue
+        }
+
+        _, err := tx.ExecContext(ctx, `
+            UPDATE outbox_events
+            SET attempts = attempts + 1, published_at = ?, last_error = NULL
+            WHERE id = ?
+        `, now, event.ID)
+        if err != nil {
+            return 0, fmt.Errorf("failed to mark outbox event published: %w", classify(err))
+        }
+        published++
+    }
+
+    if err := tx.Commit(); err != nil {
+        return 0, fmt.Errorf("failed to commit outbox batch: %w", classify(err))
+    }
+
+    return published, nil
+}
+
+// DeletePublishedBefore prunes relayed events older than the cutoff.
+func (r *OutboxRepository) DeletePublishedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+    result, err := r.db.ExecContext(ctx, `
+        DELETE FROM outbox_events
+        WHERE published_at IS NOT NULL AND published_at < ?
+    `, cutoff)
+    if err != nil {
+        return 0, fmt.Errorf("failed to delete published outbox events: %w", classify(err))
+    }
+
+    return result.RowsAffected()
+}
@@ -0,0 +1,37 @@
+package mysql
+
+import (
+    "chat-service/internal/model"
+)
+
+// keyset builds the WHERE fragment, ORDER BY direction and LIMIT for a page
+// over column. Pages fetched backwards (Before) are scanned in the opposite
+// direction, so reverse reports whether the caller must flip the rows back
+// into display order. One extra row is requested to detect further pages.
+func keyset(column string, page model.PageQuery) (where string, args []interface{}, order string, limit int, reverse bool) {
+    forward := page.Before == 0
+
+    switch {
+    case forward && page.After > 0:
+        args = append(args, page.After)
+        if page.Descending {
+            where = " AND " + column + " < ?"
+        } else {
+            where = " AND " + column + " > ?"
+        }
+    case !forward:
+        args = append(args, page.Before)
+        if page.Descending {
+            where = " AND " + column + " > ?"
+        } else {
+            where = " AND " + column + " < ?"
+        }
+    }
+
+    order = "ASC"
+    if forward == page.Descending {
+        order = "DESC"
+    }
+
+    return where, args, order, page.Limit + 1, !forward
+}
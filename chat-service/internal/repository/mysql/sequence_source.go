@@ -0,0 +1,92 @@
+package mysql
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+)
+
+// SequenceSource reads the highest chat and message numbers already stored,
+// which is the authoritative floor for the Redis sequence counters.
+type SequenceSource struct {
+    db *sql.DB
+}
+
+func NewSequenceSource(db *sql.DB) *SequenceSource {
+    return &SequenceSource{db: db}
+}
+
+func (s *SequenceSource) MaxChatNumber(ctx context.Context, applicationToken string) (int, error) {
+    var max int
+    err := s.db.QueryRowContext(ctx,
+        `SELECT COALESCE(MAX(number), 0) FROM chats WHERE application_id = ?`,
+        applicationToken).Scan(&max)
+    if err != nil {
+        return 0, fmt.Errorf("failed to query max chat number: %w", classify(err))
+    }
+    return max, nil
+}
+
+func (s *SequenceSource) MaxMessageNumber(ctx context.Context, chatID uint64) (int, error) {
+    var max int
+    err := s.db.QueryRowContext(ctx,
+        `SELECT COALESCE(MAX(number), 0) FROM messages WHERE chat_id = ?`,
+        chatID).Scan(&max)
+    if err != nil {
+        return 0, fmt.Errorf("failed to query max message number: %w", classify(err))
+    }
+    return max, nil
+}
+
+// EachChatSequence streams the highest chat number of every application.
+func (s *SequenceSource) EachChatSequence(ctx context.Context, fn func(applicationToken string, max int) error) error {
+    rows, err := s.db.QueryContext(ctx,
+        `SELECT application_id, MAX(number) FROM chats GROUP BY application_id`)
+    if err != nil {
+        return fmt.Errorf("failed to query chat sequences: %w", classify(err))
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        var token string
+        var max int
+        if err := rows.Scan(&token, &max); err != nil {
+            return fmt.Errorf("failed to scan chat sequence: %w", err)
+        }
+        if err := fn(token, max); err != nil {
+            return err
+        }
+    }
+
+    if err := rows.Err(); err != nil {
+        return fmt.Errorf("error iterating chat sequences: %w", classify(err))
+    }
+    return nil
+}
+
+// EachMessageSequence streams the highest message number of every chat that
+// has messages.
+func (s *SequenceSource) EachMessageSequence(ctx context.Context, fn func(chatID uint64, max int) error) error {
+    rows, err := s.db.QueryContext(ctx,
+        `SELECT chat_id, MAX(number) FROM messages GROUP BY chat_id`)
+    if err != nil {
+        return fmt.Errorf("failed to query message sequences: %w", classify(err))
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        var chatID uint64
+        var max int
+        if err := rows.Scan(&chatID, &max); err != nil {
+            return fmt.Errorf("failed to scan message sequence: %w", err)
+        }
+        if err := fn(chatID, max); err != nil {
+            return err
+        }
+    }
+
+    if err := rows.Err(); err != nil {
+        return fmt.Errorf("error iterating message sequences: %w", classify(err))
+    }
+    return nil
+}
@@ -0,0 +1,40 @@
+package redis
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    "github.com/go-redis/redis/v8"
+)
+
+// ApplicationCache remembers application tokens already confirmed to exist
+// in MySQL. Only positive answers are cached so applications created in the
+// Rails service are visible immediately.
+type ApplicationCache struct {
+    client *redis.Client
+    ttl    time.Duration
+}
+
+func NewApplicationCache(client *redis.Client, ttl time.Duration) *ApplicationCache {
+    return &ApplicationCache{client: client, ttl: ttl}
+}
+
+func (c *ApplicationCache) IsKnown(ctx context.Context, token string) (bool, error) {
+    n, err := c.client.Exists(ctx, applicationKey(token)).Result()
+    if err != nil {
+        return false, fmt.Errorf("failed to check application cache: %w", classify(err))
+    }
+    return n > 0, nil
+}
+
+func (c *ApplicationCache) MarkKnown(ctx context.Context, token string) error {
+    if err := c.client.Set(ctx, applicationKey(token), 1, c.ttl).Err(); err != nil {
+        return fmt.Errorf("failed to cache application: %w", classify(err))
+    }
+    return nil
+}
+
+func applicationKey(token string) string {
+    return fmt.Sprintf("app:%s:known", token)
+}
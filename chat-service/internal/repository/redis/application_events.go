@@ -0,0 +1,104 @@
+package redis
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "time"
+
+    "github.com/go-redis/redis/v8"
+
+    "chat-service/internal/model"
+)
+
+// ApplicationEvents keeps the recent activity of every application in a
+// Redis stream capped at roughly maxLen entries. Stream entry ids double as
+// event ids, so a reader can resume right after the last event it saw.
+type ApplicationEvents struct {
+    client *redis.Client
+    maxLen int64
+}
+
+func NewApplicationEvents(client *redis.Client, maxLen int64) *ApplicationEvents {
+    return &ApplicationEvents{client: client, maxLen: maxLen}
+}
+
+func (e *ApplicationEvents) Append(ctx context.Context, applicationToken string, eventType string, payload interface{}) error {
+    data, err := json.Marshal(payload)
+    if err != nil {
+        return fmt.Errorf("failed to marshal application event: %w", err)
+    }
+
+    err = e.client.XAdd(ctx, &redis.XAddArgs{
+        Stream: applicationEventsKey(applicationToken),
+        MaxLen: e.maxLen,
+        Approx: true,
+        Values: map[string]interface{}{
+            "type": eventType,
+            "data": data,
+        },
+    }).Err()
+    if err != nil {
+        return fmt.Errorf("failed to append application event: %w", classify(err))
+    }
+    return nil
+}
+
+// LastID returns the id of the newest event, or "0-0" when there is none.
+func (e *ApplicationEvents) LastID(ctx context.Context, applicationToken string) (string, error) {
+    entries, err := e.client.XRevRangeN(ctx, applicationEventsKey(applicationToken), "+", "-", 1).Result()
+    if err != nil {
+        return "", fmt.Errorf("failed to read last application event: %w", classify(err))
+    }
+    if len(entries) == 0 {
+        return "0-0", nil
+    }
+    return entries[0].ID, nil
+}
+
+// FirstID returns the id of the oldest retained event, or an empty string
+// when there is none.
+func (e *ApplicationEvents) FirstID(ctx context.Context, applicationToken string) (string, error) {
+    entries, err := e.client.XRangeN(ctx, applicationEventsKey(applicationToken), "-", "+", 1).Result()
+    if err != nil {
+        return "", fmt.Errorf("failed to read first application event: %w", classify(err))
+    }
+    if len(entries) == 0 {
+        return "", nil
+    }
+    return entries[0].ID, nil
+}
+
+// ReadAfter returns up to count events newer than afterID, waiting up to
+// block for one to arrive. No events and no error means the wait timed out.
+func (e *ApplicationEvents) ReadAfter(ctx context.Context, applicationToken string, afterID string, count int64, block time.Duration) ([]model.ApplicationEvent, error) {
+    streams, err := e.client.XRead(ctx, &redis.XReadArgs{
+        Streams: []string{applicationEventsKey(applicationToken), afterID},
+        Count:   count,
+        Block:   block,
+    }).Result()
+    if err == redis.Nil {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, fmt.Errorf("failed to read application events: %w", classify(err))
+    }
+
+    var events []model.ApplicationEvent
+    for _, stream := range streams {
+        for _, message := range stream.Messages {
+            eventType, _ := message.Values["type"].(string)
+            data, _ := message.Values["data"].(string)
+            events = append(events, model.ApplicationEvent{
+                ID:   message.ID,
+                Type: eventType,
+                Data: json.RawMessage(data),
+            })
+        }
+    }
+    return events, nil
+}
+
+func applicationEventsKey(applicationToken string) string {
+    return fmt.Sprintf("app:%s:events", applicationToken)
+}
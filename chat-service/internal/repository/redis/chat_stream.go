@@ -0,0 +1,91 @@
+package redis
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "sync"
+
+    "github.com/go-redis/redis/v8"
+
+    "chat-service/internal/model"
+)
+
+// ChatStream fans message changes of a chat out to every chat-service
+// replica through Redis pub/sub. Delivery is at most once: subscribers that
+// are not connected when an event is published never see it.
+type ChatStream struct {
+    client *redis.Client
+}
+
+func NewChatStream(client *redis.Client) *ChatStream {
+    return &ChatStream{client: client}
+}
+
+func (s *ChatStream) Publish(ctx context.Context, chatID uint64, event *model.ChatStreamEvent) error {
+    payload, err := json.Marshal(event)
+    if err != nil {
+        return fmt.Errorf("failed to marshal stream event: %w", err)
+    }
+    if err := s.client.Publish(ctx, chatStreamKey(chatID), payload).Err(); err != nil {
+        return fmt.Errorf("failed to publish stream event: %w", classify(err))
+    }
+    return nil
+}
+
+// Subscribe starts listening to the events of a chat. The subscription is
+// active once Subscribe returns, so nothing published afterwards is missed.
+func (s *ChatStream) Subscribe(ctx context.Context, chatID uint64) (model.ChatSubscription, error) {
+    pubsub := s.client.Subscribe(ctx, chatStreamKey(chatID))
+    if _, err := pubsub.Receive(ctx); err != nil {
+        pubsub.Close()
+        return nil, fmt.Errorf("failed to subscribe to chat stream: %w", classify(err))
+    }
+
+    subscription := &ChatSubscription{
+        pubsub: pubsub,
+        events: make(chan *model.ChatStreamEvent),
+        done:   make(chan struct{}),
+    }
+    go subscription.run()
+    return subscription, nil
+}
+
+func chatStreamKey(chatID uint64) string {
+    return fmt.Sprintf("chat:%d:stream", chatID)
+}
+
+// ChatSubscription is the model.ChatSubscription of a Redis pub/sub
+// channel.
+type ChatSubscription struct {
+    pubsub *redis.PubSub
+    events chan *model.ChatStreamEvent
+    done   chan struct{}
+    once   sync.Once
+}
+
+// Events is closed when the subscription is closed.
+func (s *ChatSubscription) Events() <-chan *model.ChatStreamEvent {
+    return s.events
+}
+
+func (s *ChatSubscription) Close() error {
+    s.once.Do(func() { close(s.done) })
+    return s.pubsub.Close()
+}
+
+func (s *ChatSubscription) run() {
+    defer close(s.events)
+
+    for message := range s.pubsub.Channel() {
+        event := &model.ChatStreamEvent{}
+        if err := json.Unmarshal([]byte(message.Payload), event); err != nil {
+            continue
+        }
+        select {
+        case s.events <- event:
+        case <-s.done:
+            return
+        }
+    }
+}
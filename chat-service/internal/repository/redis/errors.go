@@ -0,0 +1,25 @@
+package redis
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net"
+
+    "chat-service/internal/repository"
+)
+
+// classify tags err with repository.ErrUnavailable when Redis could not be
+// reached.
+func classify(err error) error {
+    if err == nil {
+        return nil
+    }
+
+    var netErr net.Error
+    if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) {
+        return fmt.Errorf("%w: %w", repository.ErrUnavailable, err)
+    }
+
+    return err
+}
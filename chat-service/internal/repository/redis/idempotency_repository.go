@@ -0,0 +1,89 @@
+package redis
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "time"
+
+    "github.com/go-redis/redis/v8"
+
+    "chat-service/internal/model"
+)
+
+// IdempotencyRepository keeps Idempotency-Key records. A claim only holds a
+// short lease, so a key whose request died with its process frees up soon;
+// completed responses are kept for ttl.
+type IdempotencyRepository struct {
+    client *redis.Client
+    ttl    time.Duration
+    lease  time.Duration
+}
+
+func NewIdempotencyRepository(client *redis.Client, ttl time.Duration, lease time.Duration) *IdempotencyRepository {
+    return &IdempotencyRepository{client: client, ttl: ttl, lease: lease}
+}
+
+// Reserve claims key for a new request for the lease duration. It returns
+// nil when the claim succeeded, or the record already stored under key
+// otherwise.
+func (r *IdempotencyRepository) Reserve(ctx context.Context, key string, fingerprint string) (*model.IdempotentResponse, error) {
+    pending, err := json.Marshal(model.IdempotentResponse{Fingerprint: fingerprint})
+    if err != nil {
+        return nil, fmt.Errorf("failed to marshal idempotency record: %w", err)
+    }
+
+    // A second pass is only needed when the key expires or is released
+    // between SETNX and GET.
+    for attempt := 0; attempt < 2; attempt++ {
+        claimed, err := r.client.SetNX(ctx, idempotencyKey(key), pending, r.lease).Result()
+        if err != nil {
+            return nil, fmt.Errorf("failed to reserve idempotency key: %w", classify(err))
+        }
+        if claimed {
+            return nil, nil
+        }
+
+        raw, err := r.client.Get(ctx, idempotencyKey(key)).Bytes()
+        if err == redis.Nil {
+            continue
+        }
+        if err != nil {
+            return nil, fmt.Errorf("failed to read idempotency key: %w", classify(err))
+        }
+
+        record := &model.IdempotentResponse{}
+        if err := json.Unmarshal(raw, record); err != nil {
+            return nil, fmt.Errorf("failed to unmarshal idempotency record: %w", err)
+        }
+        return record, nil
+    }
+
+    return nil, fmt.Errorf("idempotency key %s kept disappearing", key)
+}
+
+// Complete stores the final response for key for the full TTL, measured
+// from completion.
+func (r *IdempotencyRepository) Complete(ctx context.Context, key string, response *model.IdempotentResponse) error {
+    raw, err := json.Marshal(response)
+    if err != nil {
+        return fmt.Errorf("failed to marshal idempotency record: %w", err)
+    }
+
+    if err := r.client.Set(ctx, idempotencyKey(key), raw, r.ttl).Err(); err != nil {
+        return fmt.Errorf("failed to store idempotency record: %w", classify(err))
+    }
+    return nil
+}
+
+// Release forgets key so the request can be retried.
+func (r *IdempotencyRepository) Release(ctx context.Context, key string) error {
+    if err := r.client.Del(ctx, idempotencyKey(key)).Err(); err != nil {
+        return fmt.Errorf("failed to release idempotency key: %w", classify(err))
+    }
+    return nil
+}
+
+func idempotencyKey(key string) string {
+    return fmt.Sprintf("idempotency:%s", key)
+}
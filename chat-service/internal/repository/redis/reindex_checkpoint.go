@@ -0,0 +1,49 @@
+package redis
+
+import (
+    "context"
+    "fmt"
+
+    "github.com/go-redis/redis/v8"
+)
+
+// ReindexCheckpoint stores the id of the last message bulk-loaded into an
+// index, so an interrupted reindex can resume instead of starting over.
+type ReindexCheckpoint struct {
+    client *redis.Client
+}
+
+func NewReindexCheckpoint(client *redis.Client) *ReindexCheckpoint {
+    return &ReindexCheckpoint{client: client}
+}
+
+// Load returns the last loaded message id for index, or 0 when nothing was
+// loaded yet.
+func (c *ReindexCheckpoint) Load(ctx context.Context, index string) (uint64, error) {
+    lastID, err := c.client.Get(ctx, checkpointKey(index)).Uint64()
+    if err == redis.Nil {
+        return 0, nil
+    }
+    if err != nil {
+        return 0, fmt.Errorf("failed to load reindex checkpoint: %w", classify(err))
+    }
+    return lastID, nil
+}
+
+func (c *ReindexCheckpoint) Save(ctx context.Context, index string, lastID uint64) error {
+    if err := c.client.Set(ctx, checkpointKey(index), lastID, 0).Err(); err != nil {
+        return fmt.Errorf("failed to save reindex checkpoint: %w", classify(err))
+    }
+    return nil
+}
+
+func (c *ReindexCheckpoint) Clear(ctx context.Context, index string) error {
+    if err := c.client.Del(ctx, checkpointKey(index)).Err(); err != nil {
+        return fmt.Errorf("failed to clear reindex checkpoint: %w", classify(err))
+    }
+    return nil
+}
+
+func checkpointKey(index string) string {
+    return fmt.Sprintf("reindex:%s:last_id", index)
+}
@@ -6,32 +6,160 @@ import (
     "github.com/go-redis/redis/v8"
 )
 
+// SequenceSource supplies the highest numbers already persisted, used to
+// seed counters that are missing from Redis and to resync stale ones.
+type SequenceSource interface {
+    MaxChatNumber(ctx context.Context, applicationToken string) (int, error)
+    MaxMessageNumber(ctx context.Context, chatID uint64) (int, error)
+}
+
+// incrByIfExists increments a counter by ARGV[1] only when it is present,
+// so a flushed key is never silently restarted from 1.
+var incrByIfExists = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+    return redis.call("INCRBY", KEYS[1], ARGV[1])
+end
+return false
+`)
+
+// raiseTo sets a counter to ARGV[1] unless it already holds a higher value.
+var raiseTo = redis.NewScript(`
+local current = tonumber(redis.call("GET", KEYS[1]) or "0")
+local floor = tonumber(ARGV[1])
+if current < floor then
+    redis.call("SET", KEYS[1], floor)
+    return floor
+end
+return current
+`)
+
 type SequenceRepository struct {
     client *redis.Client
+    source SequenceSource
 }
 
-func NewSequenceRepository(client *redis.Client) *SequenceRepository {
-    return &SequenceRepository{client: client}
+func NewSequenceRepository(client *redis.Client, source SequenceSource) *SequenceRepository {
+    return &SequenceRepository{client: client, source: source}
 }
 
 func (r *SequenceRepository) NextChatNumber(ctx context.Context, applicationID string) (int, error) {
-    key := fmt.Sprintf("app:%s:chat_seq", applicationID)
+    key := chatSequenceKey(applicationID)
     fmt.Printf("Requesting next chat number for application: %s, key: %s\n", applicationID, key)
-    return r.getNextSequence(ctx, key)
+    return r.getNextSequence(ctx, key, func(ctx context.Context) (int, error) {
+        return r.source.MaxChatNumber(ctx, applicationID)
+    })
 }
 
 func (r *SequenceRepository) NextMessageNumber(ctx context.Context, chatID uint64) (int, error) {
-    key := fmt.Sprintf("chat:%d:msg_seq", chatID)
+    key := messageSequenceKey(chatID)
     fmt.Printf("Requesting next message number for chatID: %d, key: %s\n", chatID, key)
-    return r.getNextSequence(ctx, key)
+    return r.getNextSequence(ctx, key, func(ctx context.Context) (int, error) {
+        return r.source.MaxMessageNumber(ctx, chatID)
+    })
+}
+
+// ReserveMessageNumbers allocates count consecutive message numbers in one
+// round trip and returns the first of them.
+func (r *SequenceRepository) ReserveMessageNumbers(ctx context.Context, chatID uint64, count int) (int, error) {
+    key := messageSequenceKey(chatID)
+    last, err := r.reserve(ctx, key, count, func(ctx context.Context) (int, error) {
+        return r.source.MaxMessageNumber(ctx, chatID)
+    })
+    if err != nil {
+        return 0, err
+    }
+    return last - count + 1, nil
 }
 
-func (r *SequenceRepository) getNextSequence(ctx context.Context, key string) (int, error) {
-    fmt.Printf("Incrementing Redis key: %s\n", key)
-    val, err := r.client.Incr(ctx, key).Result()
+// ResyncChatNumber raises the chat counter of an application to the highest
+// chat number in MySQL, e.g. after Redis was restored from an old snapshot.
+func (r *SequenceRepository) ResyncChatNumber(ctx context.Context, applicationID string) error {
+    max, err := r.source.MaxChatNumber(ctx, applicationID)
     if err != nil {
-        return 0, fmt.Errorf("failed to increment sequence: %w", err)
+        return err
     }
-    fmt.Printf("Successfully incremented key: %s, new value: %d\n", key, val)
-    return int(val), nil
+    return r.RaiseChatSequence(ctx, applicationID, max)
+}
+
+// ResyncMessageNumber is the message counterpart of ResyncChatNumber.
+func (r *SequenceRepository) ResyncMessageNumber(ctx context.Context, chatID uint64) error {
+    max, err := r.source.MaxMessageNumber(ctx, chatID)
+    if err != nil {
+        return err
+    }
+    return r.RaiseMessageSequence(ctx, chatID, max)
+}
+
+func (r *SequenceRepository) RaiseChatSequence(ctx context.Context, applicationID string, floor int) error {
+    return r.raise(ctx, chatSequenceKey(applicationID), floor)
+}
+
+func (r *SequenceRepository) RaiseMessageSequence(ctx context.Context, chatID uint64, floor int) error {
+    return r.raise(ctx, messageSequenceKey(chatID), floor)
+}
+
+// SetChatSequence overwrites the counter unconditionally.
+func (r *SequenceRepository) SetChatSequence(ctx context.Context, applicationID string, value int) error {
+    return r.set(ctx, chatSequenceKey(applicationID), value)
+}
+
+// SetMessageSequence overwrites the counter unconditionally.
+func (r *SequenceRepository) SetMessageSequence(ctx context.Context, chatID uint64, value int) error {
+    return r.set(ctx, messageSequenceKey(chatID), value)
+}
+
+func (r *SequenceRepository) getNextSequence(ctx context.Context, key string, seed func(ctx context.Context) (int, error)) (int, error) {
+    return r.reserve(ctx, key, 1, seed)
+}
+
+// reserve advances the counter at key by count, seeding it first if it is
+// missing, and returns the new value.
+func (r *SequenceRepository) reserve(ctx context.Context, key string, count int, seed func(ctx context.Context) (int, error)) (int, error) {
+    // The second pass only happens if the key disappears between seeding
+    // and incrementing, e.g. a concurrent FLUSHALL.
+    for attempt := 0; attempt < 2; attempt++ {
+        fmt.Printf("Incrementing Redis key: %s\n", key)
+        val, err := incrByIfExists.Run(ctx, r.client, []string{key}, count).Int64()
+        if err == nil {
+            fmt.Printf("Successfully incremented key: %s, new value: %d\n", key, val)
+            return int(val), nil
+        }
+        if err != redis.Nil {
+            return 0, fmt.Errorf("failed to increment sequence: %w", classify(err))
+        }
+
+        max, err := seed(ctx)
+        if err != nil {
+            return 0, fmt.Errorf("failed to seed sequence %s: %w", key, err)
+        }
+        fmt.Printf("Seeding missing Redis key: %s, value: %d\n", key, max)
+        // SETNX keeps whatever a concurrent request seeded first.
+        if err := r.client.SetNX(ctx, key, max, 0).Err(); err != nil {
+            return 0, fmt.Errorf("failed to seed sequence %s: %w", key, classify(err))
+        }
+    }
+
+    return 0, fmt.Errorf("sequence %s disappeared while seeding", key)
+}
+
+func (r *SequenceRepository) raise(ctx context.Context, key string, floor int) error {
+    if err := raiseTo.Run(ctx, r.client, []string{key}, floor).Err(); err != nil {
+        return fmt.Errorf("failed to raise sequence %s: %w", key, classify(err))
+    }
+    return nil
+}
+
+func (r *SequenceRepository) set(ctx context.Context, key string, value int) error {
+    if err := r.client.Set(ctx, key, value, 0).Err(); err != nil {
+        return fmt.Errorf("failed to set sequence %s: %w", key, classify(err))
+    }
+    return nil
+}
+
+func chatSequenceKey(applicationID string) string {
+    return fmt.Sprintf("app:%s:chat_seq", applicationID)
+}
+
+func messageSequenceKey(chatID uint64) string {
+    return fmt.Sprintf("chat:%d:msg_seq", chatID)
 }
@@ -0,0 +1,128 @@
+package service
+
+import (
+    "context"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+
+    "go.uber.org/zap"
+
+    "chat-service/internal/model"
+    "chat-service/internal/repository/redis"
+)
+
+const (
+    // feedBlock bounds how long Next waits for new events, so callers get
+    // a chance to send keep-alives and notice a closed client. A blocked
+    // read is not interrupted by cancellation, so this also bounds how long
+    // an open feed delays shutdown.
+    feedBlock     = 5 * time.Second
+    feedReadCount = 100
+)
+
+var eventIDPattern = regexp.MustCompile(`^[0-9]+-[0-9]+$`)
+
+// recordApplicationEvent appends a created chat or message to the activity
+// feed of its application, with the data of the event RabbitMQ gets. Like
+// the chat stream this is best effort: the change is already committed and
+// RabbitMQ still gets it via the outbox.
+func recordApplicationEvent(ctx context.Context, events ApplicationEventLog, logger *zap.Logger, applicationToken string, eventType string, payload interface{}) {
+    if err := events.Append(context.WithoutCancel(ctx), applicationToken, eventType, payload); err != nil {
+        logger.Warn("failed to append application event",
+            zap.Error(err),
+            zap.String("event_type", eventType),
+            zap.String("application_token", applicationToken))
+    }
+}
+
+type EventService struct {
+    events *redis.ApplicationEvents
+    logger *zap.Logger
+}
+
+func NewEventService(events *redis.ApplicationEvents, logger *zap.Logger) *EventService {
+    return &EventService{
+        events: events,
+        logger: logger,
+    }
+}
+
+// OpenFeed positions a reader on the activity feed of an application. With
+// an empty lastEventID only events appended from now on are delivered;
+// otherwise delivery resumes right after lastEventID. Resync reports that
+// lastEventID is older than everything still retained, so events in between
+// may have been trimmed.
+func (s *EventService) OpenFeed(ctx context.Context, applicationToken string, lastEventID string) (*ApplicationFeed, error) {
+    if lastEventID == "" {
+        lastID, err := s.events.LastID(ctx, applicationToken)
+        if err != nil {
+            return nil, storageError("failed to open application events", err)
+        }
+        return &ApplicationFeed{applicationToken: applicationToken, lastID: lastID, events: s.events}, nil
+    }
+
+    if !eventIDPattern.MatchString(lastEventID) {
+        return nil, InvalidArgument("Last-Event-ID must be an event id such as 1700000000000-0")
+    }
+
+    firstID, err := s.events.FirstID(ctx, applicationToken)
+    if err != nil {
+        return nil, storageError("failed to open application events", err)
+    }
+
+    return &ApplicationFeed{
+        applicationToken: applicationToken,
+        lastID:           lastEventID,
+        events:           s.events,
+        Resync:           firstID != "" && compareEventIDs(lastEventID, firstID) < 0,
+    }, nil
+}
+
+// ApplicationFeed reads the events of one application in order.
+type ApplicationFeed struct {
+    applicationToken string
+    lastID           string
+    events           *redis.ApplicationEvents
+
+    Resync bool
+}
+
+// Next returns the events following the last one returned, waiting up to
+// feedBlock for any to arrive. An empty result means nothing happened in
+// the meantime.
+func (f *ApplicationFeed) Next(ctx context.Context) ([]model.ApplicationEvent, error) {
+    events, err := f.events.ReadAfter(ctx, f.applicationToken, f.lastID, feedReadCount, feedBlock)
+    if err != nil {
+        return nil, storageError("failed to read application events", err)
+    }
+    if len(events) > 0 {
+        f.lastID = events[len(events)-1].ID
+    }
+    return events, nil
+}
+
+// compareEventIDs orders two ids of the form <milliseconds>-<sequence>.
+func compareEventIDs(a, b string) int {
+    aTime, aSeq := splitEventID(a)
+    bTime, bSeq := splitEventID(b)
+    switch {
+    case aTime < bTime:
+        return -1
+    case aTime > bTime:
+        return 1
+    case aSeq < bSeq:
+        return -1
+    case aSeq > bSeq:
+        return 1
+    }
+    return 0
+}
+
+func splitEventID(id string) (uint64, uint64) {
+    timePart, seqPart, _ := strings.Cut(id, "-")
+    ms, _ := strconv.ParseUint(timePart, 10, 64)
+    seq, _ := strconv.ParseUint(seqPart, 10, 64)
+    return ms, seq
+}
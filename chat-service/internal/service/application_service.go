@@ -0,0 +1,59 @@
+package service
+
+import (
+    "context"
+
+    "go.uber.org/zap"
+
+    "chat-service/internal/repository/mysql"
+    "chat-service/internal/repository/redis"
+)
+
+type ApplicationService struct {
+    applicationRepo  *mysql.ApplicationRepository
+    applicationCache *redis.ApplicationCache
+    logger           *zap.Logger
+}
+
+func NewApplicationService(
+    applicationRepo *mysql.ApplicationRepository,
+    applicationCache *redis.ApplicationCache,
+    logger *zap.Logger,
+) *ApplicationService {
+    return &ApplicationService{
+        applicationRepo:  applicationRepo,
+        applicationCache: applicationCache,
+        logger:           logger,
+    }
+}
+
+// EnsureExists returns ErrApplicationNotFound unless token belongs to an
+// existing application. Redis is consulted first; a cache failure falls
+// through to MySQL rather than failing the request.
+func (s *ApplicationService) EnsureExists(ctx context.Context, token string) error {
+    known, err := s.applicationCache.IsKnown(ctx, token)
+    if err != nil {
+        s.logger.Warn("application cache lookup failed",
+            zap.Error(err),
+            zap.String("application_token", token))
+    }
+    if known {
+        return nil
+    }
+
+    exists, err := s.applicationRepo.ExistsByToken(ctx, token)
+    if err != nil {
+        return storageError("failed to look up application", err)
+    }
+    if !exists {
+        return ErrApplicationNotFound
+    }
+
+    if err := s.applicationCache.MarkKnown(ctx, token); err != nil {
+        s.logger.Warn("failed to cache application",
+            zap.Error(err),
+            zap.String("application_token", token))
+    }
+
+    return nil
+}
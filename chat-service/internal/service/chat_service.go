@@ -2,71 +2,103 @@ package service
 
 import (
     "context"
-    "fmt"
+    "errors"
+    "strconv"
     "time"
     
     "go.uber.org/zap"
     
     "chat-service/internal/model"
-    "chat-service/internal/repository/mysql"
-    "chat-service/internal/repository/redis"
-    "chat-service/pkg/rabbitmq"
+    "chat-service/internal/repository"
 )
 
+// maxSequenceAttempts bounds how often a create is retried after its
+// allocated number turned out to be taken already.
+const maxSequenceAttempts = 3
+
 type ChatService struct {
-    chatRepo     *mysql.ChatRepository
-    sequenceRepo *redis.SequenceRepository
-    rabbitMQ     *rabbitmq.Client
+    chatRepo     ChatRepository
+    sequenceRepo SequenceRepository
+    events       ApplicationEventLog
     logger       *zap.Logger
 }
 
 func NewChatService(
-    chatRepo *mysql.ChatRepository,
-    sequenceRepo *redis.SequenceRepository,
-    rabbitMQ *rabbitmq.Client,
+    chatRepo ChatRepository,
+    sequenceRepo SequenceRepository,
+    events ApplicationEventLog,
     logger *zap.Logger,
 ) *ChatService {
     return &ChatService{
         chatRepo:     chatRepo,
         sequenceRepo: sequenceRepo,
-        rabbitMQ:     rabbitMQ,
+        events:       events,
         logger:       logger,
     }
 }
 
 func (s *ChatService) CreateChat(ctx context.Context, applicationID string) (*model.Chat, error) {
-    number, err := s.sequenceRepo.NextChatNumber(ctx, applicationID)
-    if err != nil {
-        return nil, fmt.Errorf("failed to get next chat number: %w", err)
-    }
+    for attempt := 1; ; attempt++ {
+        number, err := s.sequenceRepo.NextChatNumber(ctx, applicationID)
+        if err != nil {
+            return nil, storageError("failed to get next chat number", err)
+        }
+
+        chat := &model.Chat{
+            ApplicationID: applicationID,
+            Number:       number,
+            CreatedAt:    time.Now().UTC(),
+        }
 
-    chat := &model.Chat{
-        ApplicationID: applicationID,
-        Number:       number,
-        CreatedAt:    time.Now().UTC(),
+        err = s.chatRepo.Create(ctx, chat)
+        if err == nil {
+            recordApplicationEvent(ctx, s.events, s.logger, applicationID, model.EventChatCreated, model.ChatEventData(chat))
+            return chat, nil
+        }
+        if !errors.Is(err, repository.ErrDuplicate) || attempt == maxSequenceAttempts {
+            return nil, storageError("failed to create chat", err)
+        }
+
+        // The Redis counter is behind MySQL; move it past the stored
+        // maximum and allocate again.
+        s.logger.Warn("chat number already taken, resyncing sequence",
+            zap.String("application_id", applicationID),
+            zap.Int("number", number))
+        if err := s.sequenceRepo.ResyncChatNumber(ctx, applicationID); err != nil {
+            return nil, storageError("failed to resync chat sequence", err)
+        }
     }
+}
 
-    if err := s.chatRepo.Create(ctx, chat); err != nil {
-        return nil, fmt.Errorf("failed to create chat: %w", err)
+func (s *ChatService) GetChat(ctx context.Context, applicationToken string, chatNumber string) (*model.Chat, error) {
+    chatNum, err := strconv.Atoi(chatNumber)
+    if err != nil {
+        return nil, ErrInvalidChatNumber.wrap(err)
     }
 
-    go func() {
-        if err := s.rabbitMQ.PublishChatCreated(context.Background(), chat); err != nil {
-            s.logger.Error("failed to publish chat created event",
-                zap.Error(err),
-                zap.String("application_id", applicationID),
-                zap.Int("number", number))
-        }
-    }()
+    chat, err := s.chatRepo.GetByNumber(ctx, applicationToken, chatNum)
+    if err != nil {
+        return nil, storageError("failed to get chat", err)
+    }
+    if chat == nil {
+        return nil, ErrChatNotFound
+    }
 
     return chat, nil
 }
 
-func (s *ChatService) ListChats(ctx context.Context, applicationToken string) ([]*model.Chat, error) {
-    chats, err := s.chatRepo.ListByApplication(ctx, applicationToken)
+func (s *ChatService) ListChats(ctx context.Context, applicationToken string, page model.PageQuery) (*model.ChatPage, error) {
+    chats, hasMore, err := s.chatRepo.ListByApplication(ctx, applicationToken, page)
     if err != nil {
-        return nil, fmt.Errorf("failed to list chats: %w", err)
+        return nil, storageError("failed to list chats", err)
+    }
+
+    result := &model.ChatPage{Chats: chats}
+    if chats == nil {
+        result.Chats = []*model.Chat{}
+    } else {
+        result.NextCursor, result.PrevCursor = pageCursors(page, chats[0].Number, chats[len(chats)-1].Number, hasMore)
     }
     
-    return chats, nil
-}
\ No newline at end of file
+    return result, nil
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+    "context"
+    "sort"
+    "sync"
+    "testing"
+
+    "chat-service/pkg/events"
+)
+
+func TestCreateChatNumbersPerApplication(t *testing.T) {
+    ctx := context.Background()
+    s := newTestServices(t)
+
+    for _, tc := range []struct {
+        application string
+        want        int
+    }{
+        {"app-a", 1},
+        {"app-a", 2},
+        {"app-b", 1},
+        {"app-a", 3},
+    } {
+        chat, err := s.chats.CreateChat(ctx, tc.application)
+        if err != nil {
+            t.Fatalf("CreateChat(%s): %v", tc.application, err)
+        }
+        if chat.Number != tc.want || chat.ApplicationID != tc.application {
+            t.Errorf("CreateChat(%s) = chat %s/%d, want number %d", tc.application, chat.ApplicationID, chat.Number, tc.want)
+        }
+        if chat.ID == 0 || chat.CreatedAt.IsZero() {
+            t.Errorf("CreateChat(%s) returned chat without id or creation time: %+v", tc.application, chat)
+        }
+    }
+
+    chat, err := s.chats.GetChat(ctx, "app-b", "1")
+    if err != nil {
+        t.Fatalf("GetChat: %v", err)
+    }
+    if chat.ApplicationID != "app-b" || chat.Number != 1 {
+        t.Errorf("GetChat = chat %s/%d, want app-b/1", chat.ApplicationID, chat.Number)
+    }
+
+    if got := len(s.eventLog.events); got != 4 {
+        t.Errorf("recorded %d application events, want 4", got)
+    }
+    for _, event := range s.eventLog.events {
+        if event.eventType != "chat_created" {
+            t.Errorf("recorded a %s event, want chat_created", event.eventType)
+        }
+        // The feed carries the same data as the RabbitMQ event.
+        if _, ok := event.payload.(events.Chat); !ok {
+            t.Errorf("recorded a %T payload, want events.Chat", event.payload)
+        }
+    }
+}
+
+func TestCreateChatResyncsStaleSequence(t *testing.T) {
+    ctx := context.Background()
+    s := newTestServices(t)
+
+    for i := 0; i < 3; i++ {
+        if _, err := s.chats.CreateChat(ctx, "app"); err != nil {
+            t.Fatalf("CreateChat: %v", err)
+        }
+    }
+    // The counter falls behind the stored chats, as after restoring Redis
+    // from an old snapshot.
+    if err := s.sequences.SetChatSequence(ctx, "app", 1); err != nil {
+        t.Fatalf("SetChatSequence: %v", err)
+    }
+
+    chat, err := s.chats.CreateChat(ctx, "app")
+    if err != nil {
+        t.Fatalf("CreateChat after the counter fell behind: %v", err)
+    }
+    if chat.Number != 4 {
+        t.Errorf("chat number = %d, want 4", chat.Number)
+    }
+}
+
+func TestCreateChatConcurrently(t *testing.T) {
+    ctx := context.Background()
+    s := newTestServices(t)
+
+    const chats = 50
+    numbers := make([]int, chats)
+    errs := make([]error, chats)
+
+    var wg sync.WaitGroup
+    for i := 0; i < chats; i++ {
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            chat, err := s.chats.CreateChat(ctx, "app")
+            errs[i] = err
+            if err == nil {
+                numbers[i] = chat.Number
+            }
+        }(i)
+    }
+    wg.Wait()
+
+    for _, err := range errs {
+        if err != nil {
+            t.Fatalf("CreateChat: %v", err)
+        }
+    }
+    sort.Ints(numbers)
+    for i, number := range numbers {
+        if number != i+1 {
+            t.Fatalf("chat numbers = %v, want 1 to %d each once", numbers, chats)
+        }
+    }
+}
+
+func TestGetChatErrors(t *testing.T) {
+    ctx := context.Background()
+    s := newTestServices(t)
+
+    _, err := s.chats.GetChat(ctx, "app", "one")
+    requireErrorKind(t, err, KindInvalidArgument)
+
+    _, err = s.chats.GetChat(ctx, "app", "1")
+    requireErrorKind(t, err, KindNotFound)
+}
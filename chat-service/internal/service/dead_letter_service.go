@@ -0,0 +1,75 @@
+package service
+
+import (
+    "context"
+    "errors"
+
+    "go.uber.org/zap"
+
+    "chat-service/internal/model"
+    "chat-service/pkg/rabbitmq"
+)
+
+var ErrQueueNotFound = &Error{Kind: KindNotFound, Code: "queue_not_found", Message: "queue is not part of the RabbitMQ topology"}
+
+// DeadLetterService inspects and replays the dead-letter queues of the
+// RabbitMQ work queues.
+type DeadLetterService struct {
+    rabbitMQ *rabbitmq.Client
+    logger   *zap.Logger
+}
+
+func NewDeadLetterService(rabbitMQ *rabbitmq.Client, logger *zap.Logger) *DeadLetterService {
+    return &DeadLetterService{
+        rabbitMQ: rabbitMQ,
+        logger:   logger,
+    }
+}
+
+// List returns up to limit dead letters of queue, oldest first, leaving
+// them in place.
+func (s *DeadLetterService) List(ctx context.Context, queue string, limit int) ([]model.DeadLetter, error) {
+    letters, err := s.rabbitMQ.PeekDeadLetters(queue, limit)
+    if err != nil {
+        return nil, brokerError("failed to read dead letters", err)
+    }
+
+    deadLetters := make([]model.DeadLetter, len(letters))
+    for i, letter := range letters {
+        deadLetters[i] = model.DeadLetter{
+            Exchange:       letter.Exchange,
+            Body:           string(letter.Body),
+            Error:          letter.Error,
+            Retries:        letter.Retries,
+            DeadLetteredAt: letter.DeadLetteredAt,
+        }
+    }
+    return deadLetters, nil
+}
+
+// Replay moves up to limit dead letters of queue back to it, oldest first,
+// and returns how many were moved. A failure part-way still reports the
+// ones already moved.
+func (s *DeadLetterService) Replay(ctx context.Context, queue string, limit int) (int, error) {
+    replayed, err := s.rabbitMQ.ReplayDeadLetters(ctx, queue, limit)
+    if replayed > 0 {
+        s.logger.Info("replayed dead letters",
+            zap.String("queue", queue),
+            zap.Int("replayed", replayed))
+    }
+    if err != nil {
+        return replayed, brokerError("failed to replay dead letters", err)
+    }
+    return replayed, nil
+}
+
+// brokerError converts a RabbitMQ client failure into an Error.
+func brokerError(message string, err error) error {
+    switch {
+    case errors.Is(err, rabbitmq.ErrUnknownQueue):
+        return ErrQueueNotFound.wrap(err)
+    case errors.Is(err, rabbitmq.ErrUnavailable):
+        return &Error{Kind: KindUnavailable, Code: string(KindUnavailable), Message: message, Err: err}
+    }
+    return &Error{Kind: KindInternal, Code: string(KindInternal), Message: message, Err: err}
+}
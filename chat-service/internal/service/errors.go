@@ -0,0 +1,94 @@
+package service
+
+import (
+    "errors"
+
+    "chat-service/internal/repository"
+)
+
+// ErrorKind classifies a service failure independently of its cause. The
+// handler layer maps each kind to one HTTP status.
+type ErrorKind string
+
+const (
+    KindInvalidArgument ErrorKind = "invalid_argument"
+    KindNotFound        ErrorKind = "not_found"
+    KindConflict        ErrorKind = "conflict"
+    KindUnavailable     ErrorKind = "unavailable"
+    KindInternal        ErrorKind = "internal"
+)
+
+// Error is the error type returned by every service method. Code is a
+// stable, machine-readable identifier exposed to API clients; Message is
+// safe to show them; Err carries the underlying cause for logging.
+type Error struct {
+    Kind    ErrorKind
+    Code    string
+    Message string
+    Err     error
+}
+
+func (e *Error) Error() string {
+    if e.Err != nil {
+        return e.Message + ": " + e.Err.Error()
+    }
+    return e.Message
+}
+
+func (e *Error) Unwrap() error {
+    return e.Err
+}
+
+// Is matches on Code so a sentinel still matches after wrap attaches a cause.
+func (e *Error) Is(target error) bool {
+    t, ok := target.(*Error)
+    return ok && t.Code == e.Code
+}
+
+func (e *Error) wrap(err error) *Error {
+    wrapped := *e
+    wrapped.Err = err
+    return &wrapped
+}
+
+var (
+    ErrApplicationNotFound  = &Error{Kind: KindNotFound, Code: "application_not_found", Message: "application not found"}
+    ErrChatNotFound         = &Error{Kind: KindNotFound, Code: "chat_not_found", Message: "chat not found"}
+    ErrMessageNotFound      = &Error{Kind: KindNotFound, Code: "message_not_found", Message: "message not found"}
+    ErrInvalidChatNumber    = &Error{Kind: KindInvalidArgument, Code: "invalid_chat_number", Message: "invalid chat number"}
+    ErrInvalidMessageNumber = &Error{Kind: KindInvalidArgument, Code: "invalid_message_number", Message: "invalid message number"}
+
+    ErrInvalidIdempotencyKey = &Error{Kind: KindInvalidArgument, Code: "invalid_idempotency_key", Message: "Idempotency-Key must be at most 255 characters"}
+    ErrIdempotencyKeyReused  = &Error{Kind: KindInvalidArgument, Code: "idempotency_key_reused", Message: "Idempotency-Key was already used with a different request"}
+    ErrIdempotencyInProgress = &Error{Kind: KindConflict, Code: "idempotency_in_progress", Message: "a request with this Idempotency-Key is still in progress"}
+)
+
+// InvalidArgument builds an error for input rejected before reaching storage.
+func InvalidArgument(message string) *Error {
+    return &Error{Kind: KindInvalidArgument, Code: string(KindInvalidArgument), Message: message}
+}
+
+// MayHaveCommitted reports whether err left it unknown if the write it
+// failed was stored, so that repeating the request could apply it twice.
+func MayHaveCommitted(err error) bool {
+    return errors.Is(err, repository.ErrCommitUnknown)
+}
+
+// storageError converts a repository failure into an Error, classifying it
+// as a conflict or unavailability when the repository tagged it as such.
+func storageError(message string, err error) error {
+    var serviceErr *Error
+    if errors.As(err, &serviceErr) {
+        return err
+    }
+
+    kind := KindInternal
+    switch {
+    case errors.Is(err, repository.ErrDuplicate):
+        kind = KindConflict
+    case errors.Is(err, repository.ErrUnavailable):
+        kind = KindUnavailable
+    }
+
+    return &Error{Kind: kind, Code: string(kind), Message: message, Err: err}
+}
@@ -0,0 +1,14 @@
+package service
+
+import (
+    "context"
+
+    "chat-service/pkg/events"
+)
+
+// EventPublisher delivers the events relayed from the outbox. Errors
+// wrapping events.ErrUnavailable mean the bus cannot take events right now.
+// *rabbitmq.Client and *events.MemoryBus implement it.
+type EventPublisher interface {
+    Publish(ctx context.Context, envelope *events.Envelope) error
+}
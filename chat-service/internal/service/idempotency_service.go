@@ -0,0 +1,74 @@
+package service
+
+import (
+    "context"
+
+    "go.uber.org/zap"
+
+    "chat-service/internal/model"
+    "chat-service/internal/repository/redis"
+)
+
+const maxIdempotencyKeyLength = 255
+
+type IdempotencyService struct {
+    idempotencyRepo *redis.IdempotencyRepository
+    logger          *zap.Logger
+}
+
+func NewIdempotencyService(idempotencyRepo *redis.IdempotencyRepository, logger *zap.Logger) *IdempotencyService {
+    return &IdempotencyService{
+        idempotencyRepo: idempotencyRepo,
+        logger:          logger,
+    }
+}
+
+// Begin claims key for a request whose payload hashes to fingerprint. It
+// returns nil when the caller should execute the request, or the stored
+// response when an earlier request with the same key already completed.
+func (s *IdempotencyService) Begin(ctx context.Context, key string, fingerprint string) (*model.IdempotentResponse, error) {
+    if len(key) > maxIdempotencyKeyLength {
+        return nil, ErrInvalidIdempotencyKey
+    }
+
+    record, err := s.idempotencyRepo.Reserve(ctx, key, fingerprint)
+    if err != nil {
+        return nil, storageError("failed to reserve idempotency key", err)
+    }
+    if record == nil {
+        return nil, nil
+    }
+    if record.Fingerprint != fingerprint {
+        return nil, ErrIdempotencyKeyReused
+    }
+    if !record.Completed {
+        return nil, ErrIdempotencyInProgress
+    }
+
+    return record, nil
+}
+
+// Complete remembers the response of a finished request.
+func (s *IdempotencyService) Complete(ctx context.Context, key string, fingerprint string, status int, body []byte) {
+    err := s.idempotencyRepo.Complete(ctx, key, &model.IdempotentResponse{
+        Fingerprint: fingerprint,
+        Completed:   true,
+        Status:      status,
+        Body:        body,
+    })
+    if err != nil {
+        s.logger.Error("failed to store idempotent response",
+            zap.Error(err),
+            zap.String("idempotency_key", key))
+    }
+}
+
+// Release drops the claim on key after a request that wrote nothing, so it
+// can be retried with the same key.
+func (s *IdempotencyService) Release(ctx context.Context, key string) {
+    if err := s.idempotencyRepo.Release(ctx, key); err != nil {
+        s.logger.Error("failed to release idempotency key",
+            zap.Error(err),
+            zap.String("idempotency_key", key))
+    }
+}
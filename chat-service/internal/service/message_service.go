@@ -2,6 +2,7 @@ package service
 
 import (
     "context"
+    "errors"
     "fmt"
     "time"
     "strconv"
@@ -9,173 +10,307 @@ import (
     "go.uber.org/zap"
     
     "chat-service/internal/model"
-    "chat-service/internal/repository/mysql"
-    "chat-service/internal/repository/redis"
-    "chat-service/pkg/elasticsearch"
-    "chat-service/pkg/rabbitmq"
+    "chat-service/internal/repository"
 )
 
 
 type MessageService struct {
-    messageRepo   *mysql.MessageRepository
-    chatRepo     *mysql.ChatRepository
-    sequenceRepo  *redis.SequenceRepository
-    rabbitMQ      *rabbitmq.Client
-    elasticSearch *elasticsearch.Client
+    messageRepo   MessageRepository
+    searchRepo    SearchRepository
+    chatRepo     ChatRepository
+    sequenceRepo  SequenceRepository
+    chatStream    ChatStream
+    events        ApplicationEventLog
     logger        *zap.Logger
 }
 
 func NewMessageService(
-    messageRepo *mysql.MessageRepository,
-    chatRepo *mysql.ChatRepository,
-    sequenceRepo *redis.SequenceRepository,
-    rabbitMQ *rabbitmq.Client,
-    elasticSearch *elasticsearch.Client,
+    messageRepo MessageRepository,
+    searchRepo SearchRepository,
+    chatRepo ChatRepository,
+    sequenceRepo SequenceRepository,
+    chatStream ChatStream,
+    events ApplicationEventLog,
     logger *zap.Logger,
 ) *MessageService {
     return &MessageService{
         messageRepo:   messageRepo,
+        searchRepo:    searchRepo,
         chatRepo:     chatRepo,
         sequenceRepo:  sequenceRepo,
-        rabbitMQ:      rabbitMQ,
-        elasticSearch: elasticSearch,
+        chatStream:    chatStream,
+        events:        events,
         logger:        logger,
     }
 }
 
 func (s *MessageService) CreateMessage(ctx context.Context, applicationToken string, chatNumber string, body string) (*model.Message, error) {
-
-    chatNum, err := strconv.Atoi(chatNumber)
+    chat, err := s.getChat(ctx, applicationToken, chatNumber)
     if err != nil {
-        return nil, fmt.Errorf("invalid chat number: %w", err)
+        return nil, err
     }
 
-    chat, err := s.chatRepo.GetByNumber(ctx, applicationToken, chatNum)
-    if err != nil {
-        return nil, fmt.Errorf("failed to get chat: %w", err)
-    }
-    if chat == nil {
-        return nil, fmt.Errorf("chat not found")
-    }
+    var message *model.Message
+    for attempt := 1; ; attempt++ {
+        number, err := s.sequenceRepo.NextMessageNumber(ctx, chat.ID)
+        if err != nil {
+            return nil, storageError("failed to get next message number", err)
+        }
 
-    number, err := s.sequenceRepo.NextMessageNumber(ctx, chat.ID)
-    if err != nil {
-        return nil, fmt.Errorf("failed to get next message number: %w", err)
+        message = &model.Message{
+            ChatID:    chat.ID,
+            Number:    number,
+            Body:      body,
+            CreatedAt: time.Now().UTC(),
+        }
+
+        err = s.messageRepo.Create(ctx, chat, message)
+        if err == nil {
+            break
+        }
+        if !errors.Is(err, repository.ErrDuplicate) || attempt == maxSequenceAttempts {
+            return nil, storageError("failed to create message", err)
+        }
+
+        s.logger.Warn("message number already taken, resyncing sequence",
+            zap.Uint64("chat_id", chat.ID),
+            zap.Int("number", number))
+        if err := s.sequenceRepo.ResyncMessageNumber(ctx, chat.ID); err != nil {
+            return nil, storageError("failed to resync message sequence", err)
+        }
     }
 
-    message := &model.Message{
-        ChatID:    chat.ID,
-        Number:    number,
-        Body:      body,
-        CreatedAt: time.Now().UTC(),
+    s.publish(ctx, model.EventMessageCreated, message)
+    recordApplicationEvent(ctx, s.events, s.logger, chat.ApplicationID, model.EventMessageCreated, model.MessageEventData(message))
+
+    return message, nil
+}
+
+// CreateMessages stores up to model.MaxMessageBatchSize messages in one go,
+// numbering them consecutively in the order given.
+func (s *MessageService) CreateMessages(ctx context.Context, applicationToken string, chatNumber string, bodies []string) ([]*model.Message, error) {
+    if len(bodies) == 0 {
+        return nil, InvalidArgument("at least one message is required")
+    }
+    if len(bodies) > model.MaxMessageBatchSize {
+        return nil, InvalidArgument(fmt.Sprintf("at most %d messages can be created at once", model.MaxMessageBatchSize))
     }
 
-    if err := s.messageRepo.Create(ctx, message); err != nil {
-        return nil, fmt.Errorf("failed to create message: %w", err)
+    chat, err := s.getChat(ctx, applicationToken, chatNumber)
+    if err != nil {
+        return nil, err
     }
 
-    go func() {
-        messageJSON, err := message.ToJSON()
+    for attempt := 1; ; attempt++ {
+        first, err := s.sequenceRepo.ReserveMessageNumbers(ctx, chat.ID, len(bodies))
         if err != nil {
-            s.logger.Error("failed to marshal message",
-                zap.Error(err),
-                zap.Uint64("message_id", message.ID))
-            return
+            return nil, storageError("failed to reserve message numbers", err)
+        }
+
+        now := time.Now().UTC()
+        messages := make([]*model.Message, len(bodies))
+        for i, body := range bodies {
+            messages[i] = &model.Message{
+                ChatID:    chat.ID,
+                Number:    first + i,
+                Body:      body,
+                CreatedAt: now,
+            }
         }
 
-        if err := s.elasticSearch.Index("messages", fmt.Sprintf("%d", message.ID), messageJSON); err != nil {
-            s.logger.Error("failed to index message",
-                zap.Error(err),
-                zap.Uint64("message_id", message.ID))
+        err = s.messageRepo.CreateBatch(ctx, chat, messages)
+        if err == nil {
+            for _, message := range messages {
+                s.publish(ctx, model.EventMessageCreated, message)
+                recordApplicationEvent(ctx, s.events, s.logger, chat.ApplicationID, model.EventMessageCreated, model.MessageEventData(message))
+            }
+            return messages, nil
+        }
+        if !errors.Is(err, repository.ErrDuplicate) || attempt == maxSequenceAttempts {
+            return nil, storageError("failed to create messages", err)
         }
-    }()
 
-    go func() {
-        if err := s.rabbitMQ.PublishMessageCreated(context.Background(), message); err != nil {
-            s.logger.Error("failed to publish message created event",
-                zap.Error(err),
-                zap.Uint64("message_id", message.ID))
+        s.logger.Warn("message numbers already taken, resyncing sequence",
+            zap.Uint64("chat_id", chat.ID),
+            zap.Int("first", first),
+            zap.Int("count", len(bodies)))
+        if err := s.sequenceRepo.ResyncMessageNumber(ctx, chat.ID); err != nil {
+            return nil, storageError("failed to resync message sequence", err)
         }
-    }()
+    }
+}
+
+func (s *MessageService) GetMessage(ctx context.Context, applicationToken string, chatNumber string, messageNumber string) (*model.Message, error) {
+    chat, err := s.getChat(ctx, applicationToken, chatNumber)
+    if err != nil {
+        return nil, err
+    }
+
+    msgNum, err := strconv.Atoi(messageNumber)
+    if err != nil {
+        return nil, ErrInvalidMessageNumber.wrap(err)
+    }
+
+    message, err := s.messageRepo.GetByNumber(ctx, chat.ID, msgNum)
+    if err != nil {
+        return nil, storageError("failed to get message", err)
+    }
+    if message == nil {
+        return nil, ErrMessageNotFound
+    }
 
     return message, nil
 }
 
-func (s *MessageService) ListMessages(ctx context.Context, applicationToken string, chatNumber string) ([]*model.Message, error) {
-    chatNum, err := strconv.Atoi(chatNumber)
+func (s *MessageService) UpdateMessage(ctx context.Context, applicationToken string, chatNumber string, messageNumber string, body string) (*model.Message, error) {
+    chat, err := s.getChat(ctx, applicationToken, chatNumber)
     if err != nil {
-        return nil, fmt.Errorf("invalid chat number: %w", err)
+        return nil, err
     }
 
-    chat, err := s.chatRepo.GetByNumber(ctx, applicationToken, chatNum)
+    msgNum, err := strconv.Atoi(messageNumber)
     if err != nil {
-        return nil, fmt.Errorf("failed to get chat: %w", err)
+        return nil, ErrInvalidMessageNumber.wrap(err)
     }
-    if chat == nil {
-        return nil, fmt.Errorf("chat not found")
+
+    message, err := s.messageRepo.Update(ctx, chat, msgNum, body)
+    if err != nil {
+        return nil, storageError("failed to update message", err)
     }
+    if message == nil {
+        return nil, ErrMessageNotFound
+    }
+
+    s.publish(ctx, model.EventMessageUpdated, message)
+
+    return message, nil
+}
 
-    messages, err := s.messageRepo.ListByChat(ctx, chat.ID)
+func (s *MessageService) DeleteMessage(ctx context.Context, applicationToken string, chatNumber string, messageNumber string) error {
+    chat, err := s.getChat(ctx, applicationToken, chatNumber)
     if err != nil {
-        return nil, fmt.Errorf("failed to list messages: %w", err)
+        return err
     }
 
-    if messages == nil {
-        return []*model.Message{}, nil
+    msgNum, err := strconv.Atoi(messageNumber)
+    if err != nil {
+        return ErrInvalidMessageNumber.wrap(err)
+    }
+
+    message, err := s.messageRepo.Delete(ctx, chat, msgNum)
+    if err != nil {
+        return storageError("failed to delete message", err)
+    }
+    if message == nil {
+        return ErrMessageNotFound
     }
 
-    return messages, nil
+    s.publish(ctx, model.EventMessageDeleted, message)
+
+    return nil
 }
 
-func (s *MessageService) SearchMessages(ctx context.Context, applicationToken string, chatNumber string, query string) ([]*model.Message, error) {
-    chatNum, err := strconv.Atoi(chatNumber)
+func (s *MessageService) ListMessages(ctx context.Context, applicationToken string, chatNumber string, page model.PageQuery) (*model.MessagePage, error) {
+    chat, err := s.getChat(ctx, applicationToken, chatNumber)
     if err != nil {
-        return nil, fmt.Errorf("invalid chat number: %w", err)
+        return nil, err
     }
 
-    chat, err := s.chatRepo.GetByNumber(ctx, applicationToken, chatNum)
+    messages, hasMore, err := s.messageRepo.ListByChat(ctx, chat.ID, page)
     if err != nil {
-        return nil, fmt.Errorf("failed to get chat: %w", err)
+        return nil, storageError("failed to list messages", err)
     }
-    if chat == nil {
-        return nil, fmt.Errorf("chat not found")
-    }
-
-    searchQuery := map[string]interface{}{
-        "query": map[string]interface{}{
-            "bool": map[string]interface{}{
-                "must": []map[string]interface{}{
-                    {
-                        "match": map[string]interface{}{
-                            "body": query,
-                        },
-                    },
-                    {
-                        "term": map[string]interface{}{
-                            "chat_id": chat.ID,
-                        },
-                    },
-                },
-            },
-        },
-        "sort": []map[string]interface{}{
-            {
-                "created_at": map[string]interface{}{
-                    "order": "desc",
-                },
-            },
-        },
-    }
-
-    messages, err := s.messageRepo.Search(ctx, searchQuery)
+
+    result := &model.MessagePage{Messages: messages}
+    if messages == nil {
+        result.Messages = []*model.Message{}
+    } else {
+        result.NextCursor, result.PrevCursor = pageCursors(page, messages[0].Number, messages[len(messages)-1].Number, hasMore)
+    }
+
+    return result, nil
+}
+
+func (s *MessageService) SearchMessages(ctx context.Context, applicationToken string, chatNumber string, search model.SearchQuery) (*model.SearchResult, error) {
+    chat, err := s.getChat(ctx, applicationToken, chatNumber)
+    if err != nil {
+        return nil, err
+    }
+
+    result, err := s.search(ctx, search, model.SearchScope{
+        ApplicationToken: applicationToken,
+        ChatID:           chat.ID,
+    })
     if err != nil {
         s.logger.Error("failed to search messages",
             zap.Error(err),
             zap.String("application_token", applicationToken),
             zap.String("chat_number", chatNumber),
-            zap.String("query", query))
-        return nil, fmt.Errorf("failed to search messages: %w", err)
+            zap.String("query", search.Text))
+        return nil, storageError("failed to search messages", err)
+    }
+
+    return result, nil
+}
+
+// SearchApplicationMessages searches every chat of an application at once.
+// Each hit carries the number of the chat it belongs to.
+func (s *MessageService) SearchApplicationMessages(ctx context.Context, applicationToken string, search model.SearchQuery) (*model.SearchResult, error) {
+    result, err := s.search(ctx, search, model.SearchScope{
+        ApplicationToken: applicationToken,
+    })
+    if err != nil {
+        s.logger.Error("failed to search application messages",
+            zap.Error(err),
+            zap.String("application_token", applicationToken),
+            zap.String("query", search.Text))
+        return nil, storageError("failed to search messages", err)
+    }
+
+    return result, nil
+}
+
+// search runs a full-text query on message bodies restricted by scope, in
+// the requested order, with highlighted fragments. When Elasticsearch is unavailable it
+// answers from the MySQL FULLTEXT index instead and marks the result as
+// degraded.
+func (s *MessageService) search(ctx context.Context, search model.SearchQuery, scope model.SearchScope) (*model.SearchResult, error) {
+    result, err := s.searchRepo.Search(ctx, scope, search)
+    if errors.Is(err, repository.ErrUnavailable) {
+        s.logger.Warn("search backend unavailable, falling back to MySQL full-text search",
+            zap.Error(err),
+            zap.String("application_token", scope.ApplicationToken),
+            zap.Uint64("chat_id", scope.ChatID))
+
+        result, err = s.searchRepo.SearchFullText(ctx, scope, search)
+        if err != nil {
+            return nil, err
+        }
+        result.Degraded = true
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    result.Page = search.Page
+    result.Size = search.Size
+
+    return result, nil
+}
+
+func (s *MessageService) getChat(ctx context.Context, applicationToken string, chatNumber string) (*model.Chat, error) {
+    chatNum, err := strconv.Atoi(chatNumber)
+    if err != nil {
+        return nil, ErrInvalidChatNumber.wrap(err)
+    }
+
+    chat, err := s.chatRepo.GetByNumber(ctx, applicationToken, chatNum)
+    if err != nil {
+        return nil, storageError("failed to get chat", err)
+    }
+    if chat == nil {
+        return nil, ErrChatNotFound
     }
 
-    return messages, nil
+    return chat, nil
 }
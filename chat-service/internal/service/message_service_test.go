@@ -0,0 +1,364 @@
+package service
+
+import (
+    "context"
+    "reflect"
+    "strconv"
+    "testing"
+
+    "chat-service/internal/model"
+)
+
+// createChatWithMessages creates a chat in app holding one message per body
+// and returns the chat number.
+func createChatWithMessages(t *testing.T, s *testServices, app string, bodies ...string) string {
+    t.Helper()
+    ctx := context.Background()
+
+    chat, err := s.chats.CreateChat(ctx, app)
+    if err != nil {
+        t.Fatalf("CreateChat: %v", err)
+    }
+    chatNumber := strconv.Itoa(chat.Number)
+
+    for _, body := range bodies {
+        if _, err := s.messages.CreateMessage(ctx, app, chatNumber, body); err != nil {
+            t.Fatalf("CreateMessage(%q): %v", body, err)
+        }
+    }
+    return chatNumber
+}
+
+func messageNumbers(messages []*model.Message) []int {
+    numbers := []int{}
+    for _, message := range messages {
+        numbers = append(numbers, message.Number)
+    }
+    return numbers
+}
+
+func hitBodies(result *model.SearchResult) []string {
+    bodies := []string{}
+    for _, hit := range result.Hits {
+        bodies = append(bodies, hit.Body)
+    }
+    return bodies
+}
+
+func TestCreateMessage(t *testing.T) {
+    ctx := context.Background()
+    s := newTestServices(t)
+    first := createChatWithMessages(t, s, "app")
+    second := createChatWithMessages(t, s, "app")
+
+    for _, tc := range []struct {
+        chat string
+        want int
+    }{
+        {first, 1},
+        {first, 2},
+        {second, 1},
+        {first, 3},
+    } {
+        message, err := s.messages.CreateMessage(ctx, "app", tc.chat, "hello")
+        if err != nil {
+            t.Fatalf("CreateMessage(chat %s): %v", tc.chat, err)
+        }
+        if message.Number != tc.want || message.ID == 0 || message.Body != "hello" {
+            t.Errorf("CreateMessage(chat %s) = %+v, want number %d", tc.chat, message, tc.want)
+        }
+    }
+
+    message, err := s.messages.GetMessage(ctx, "app", first, "3")
+    if err != nil {
+        t.Fatalf("GetMessage: %v", err)
+    }
+    if message.Body != "hello" {
+        t.Errorf("GetMessage body = %q, want hello", message.Body)
+    }
+
+    if got := len(s.stream.events); got != 4 {
+        t.Errorf("streamed %d events, want 4", got)
+    }
+    for _, event := range s.stream.events {
+        if event.Type != model.EventMessageCreated {
+            t.Errorf("streamed a %s event, want %s", event.Type, model.EventMessageCreated)
+        }
+    }
+    // Two chat_created events and four message_created ones.
+    if got := len(s.eventLog.events); got != 6 {
+        t.Errorf("recorded %d application events, want 6", got)
+    }
+}
+
+func TestCreateMessageErrors(t *testing.T) {
+    ctx := context.Background()
+    s := newTestServices(t)
+
+    _, err := s.messages.CreateMessage(ctx, "app", "x", "hello")
+    requireErrorKind(t, err, KindInvalidArgument)
+
+    _, err = s.messages.CreateMessage(ctx, "app", "1", "hello")
+    requireErrorKind(t, err, KindNotFound)
+
+    // Chats belong to one application only.
+    createChatWithMessages(t, s, "app")
+    _, err = s.messages.CreateMessage(ctx, "other-app", "1", "hello")
+    requireErrorKind(t, err, KindNotFound)
+}
+
+func TestCreateMessageResyncsStaleSequence(t *testing.T) {
+    ctx := context.Background()
+    s := newTestServices(t)
+    chatNumber := createChatWithMessages(t, s, "app", "one", "two", "three")
+
+    chat, err := s.chats.GetChat(ctx, "app", chatNumber)
+    if err != nil {
+        t.Fatalf("GetChat: %v", err)
+    }
+    if err := s.sequences.SetMessageSequence(ctx, chat.ID, 0); err != nil {
+        t.Fatalf("SetMessageSequence: %v", err)
+    }
+
+    message, err := s.messages.CreateMessage(ctx, "app", chatNumber, "four")
+    if err != nil {
+        t.Fatalf("CreateMessage after the counter fell behind: %v", err)
+    }
+    if message.Number != 4 {
+        t.Errorf("message number = %d, want 4", message.Number)
+    }
+}
+
+func TestCreateMessagesNumbersConsecutively(t *testing.T) {
+    ctx := context.Background()
+    s := newTestServices(t)
+    chatNumber := createChatWithMessages(t, s, "app", "first")
+
+    messages, err := s.messages.CreateMessages(ctx, "app", chatNumber, []string{"a", "b", "c"})
+    if err != nil {
+        t.Fatalf("CreateMessages: %v", err)
+    }
+    if got, want := messageNumbers(messages), []int{2, 3, 4}; !reflect.DeepEqual(got, want) {
+        t.Errorf("batch numbers = %v, want %v", got, want)
+    }
+
+    _, err = s.messages.CreateMessages(ctx, "app", chatNumber, nil)
+    requireErrorKind(t, err, KindInvalidArgument)
+}
+
+func TestListMessages(t *testing.T) {
+    ctx := context.Background()
+    s := newTestServices(t)
+    chatNumber := createChatWithMessages(t, s, "app", "1", "2", "3", "4", "5")
+
+    tests := []struct {
+        name    string
+        page    model.PageQuery
+        want    []int
+        hasNext bool
+        hasPrev bool
+    }{
+        {"first page", model.PageQuery{Limit: 2}, []int{1, 2}, true, false},
+        {"middle page", model.PageQuery{Limit: 2, After: 2}, []int{3, 4}, true, true},
+        {"last page", model.PageQuery{Limit: 2, After: 4}, []int{5}, false, true},
+        {"backwards", model.PageQuery{Limit: 2, Before: 5}, []int{3, 4}, true, true},
+        {"backwards to the start", model.PageQuery{Limit: 2, Before: 3}, []int{1, 2}, true, false},
+        {"descending", model.PageQuery{Limit: 2, Descending: true}, []int{5, 4}, true, false},
+        {"descending after", model.PageQuery{Limit: 2, Descending: true, After: 4}, []int{3, 2}, true, true},
+        {"descending backwards", model.PageQuery{Limit: 2, Descending: true, Before: 3}, []int{5, 4}, true, false},
+        {"everything", model.PageQuery{Limit: 10}, []int{1, 2, 3, 4, 5}, false, false},
+    }
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            page, err := s.messages.ListMessages(ctx, "app", chatNumber, tc.page)
+            if err != nil {
+                t.Fatalf("ListMessages: %v", err)
+            }
+            if got := messageNumbers(page.Messages); !reflect.DeepEqual(got, tc.want) {
+                t.Errorf("numbers = %v, want %v", got, tc.want)
+            }
+            if (page.NextCursor != "") != tc.hasNext {
+                t.Errorf("next cursor = %q, want one: %v", page.NextCursor, tc.hasNext)
+            }
+            if (page.PrevCursor != "") != tc.hasPrev {
+                t.Errorf("prev cursor = %q, want one: %v", page.PrevCursor, tc.hasPrev)
+            }
+        })
+    }
+}
+
+func TestListMessagesFollowsCursors(t *testing.T) {
+    ctx := context.Background()
+    s := newTestServices(t)
+    chatNumber := createChatWithMessages(t, s, "app", "1", "2", "3", "4", "5")
+
+    var seen []int
+    page := model.PageQuery{Limit: 2}
+    for {
+        result, err := s.messages.ListMessages(ctx, "app", chatNumber, page)
+        if err != nil {
+            t.Fatalf("ListMessages: %v", err)
+        }
+        seen = append(seen, messageNumbers(result.Messages)...)
+        if result.NextCursor == "" {
+            break
+        }
+        after, err := model.DecodeCursor(result.NextCursor)
+        if err != nil {
+            t.Fatalf("DecodeCursor(%q): %v", result.NextCursor, err)
+        }
+        page.After = after
+    }
+
+    if want := []int{1, 2, 3, 4, 5}; !reflect.DeepEqual(seen, want) {
+        t.Errorf("paged through %v, want %v", seen, want)
+    }
+}
+
+func TestListMessagesEmptyChat(t *testing.T) {
+    ctx := context.Background()
+    s := newTestServices(t)
+    chatNumber := createChatWithMessages(t, s, "app")
+
+    page, err := s.messages.ListMessages(ctx, "app", chatNumber, model.PageQuery{Limit: 10})
+    if err != nil {
+        t.Fatalf("ListMessages: %v", err)
+    }
+    if page.Messages == nil || len(page.Messages) != 0 {
+        t.Errorf("messages = %v, want an empty list", page.Messages)
+    }
+    if page.NextCursor != "" || page.PrevCursor != "" {
+        t.Errorf("cursors = %q / %q, want none", page.NextCursor, page.PrevCursor)
+    }
+
+    _, err = s.messages.ListMessages(ctx, "app", "99", model.PageQuery{Limit: 10})
+    requireErrorKind(t, err, KindNotFound)
+}
+
+func TestSearchMessages(t *testing.T) {
+    ctx := context.Background()
+    s := newTestServices(t)
+    chatNumber := createChatWithMessages(t, s, "app",
+        "the build is green",
+        "deploying the new build",
+        "lunch?",
+        "Build failed again",
+    )
+    createChatWithMessages(t, s, "app", "another chat mentions the build")
+    createChatWithMessages(t, s, "other-app", "the build of someone else")
+
+    query := func(text string, mode model.SearchMode, sort model.SearchSort) model.SearchQuery {
+        return model.SearchQuery{Text: text, Mode: mode, Sort: sort, Page: 1, Size: 10}
+    }
+
+    tests := []struct {
+        name  string
+        query model.SearchQuery
+        want  []string
+    }{
+        {"match newest first", query("build", model.SearchModeMatch, model.SearchSortNewest),
+            []string{"Build failed again", "deploying the new build", "the build is green"}},
+        {"match oldest first", query("build", model.SearchModeMatch, model.SearchSortOldest),
+            []string{"the build is green", "deploying the new build", "Build failed again"}},
+        {"match any word", query("lunch green", model.SearchModeMatch, model.SearchSortOldest),
+            []string{"the build is green", "lunch?"}},
+        {"relevance", query("build green", model.SearchModeMatch, model.SearchSortRelevance),
+            []string{"the build is green", "Build failed again", "deploying the new build"}},
+        {"phrase", query("new build", model.SearchModePhrase, model.SearchSortNewest),
+            []string{"deploying the new build"}},
+        {"phrase in order only", query("build new", model.SearchModePhrase, model.SearchSortNewest),
+            []string{}},
+        {"prefix", query("build fail", model.SearchModePrefix, model.SearchSortNewest),
+            []string{"Build failed again"}},
+        {"wildcard", query("dep*ing", model.SearchModeWildcard, model.SearchSortNewest),
+            []string{"deploying the new build"}},
+        {"no match", query("release", model.SearchModeMatch, model.SearchSortNewest),
+            []string{}},
+    }
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            result, err := s.messages.SearchMessages(ctx, "app", chatNumber, tc.query)
+            if err != nil {
+                t.Fatalf("SearchMessages: %v", err)
+            }
+            if got := hitBodies(result); !reflect.DeepEqual(got, tc.want) {
+                t.Errorf("hits = %q, want %q", got, tc.want)
+            }
+            if result.Total != int64(len(tc.want)) || result.Degraded {
+                t.Errorf("total = %d, degraded = %v, want %d from the index", result.Total, result.Degraded, len(tc.want))
+            }
+        })
+    }
+}
+
+func TestSearchMessagesPagesAndHighlights(t *testing.T) {
+    ctx := context.Background()
+    s := newTestServices(t)
+    chatNumber := createChatWithMessages(t, s, "app", "ping 1", "ping 2", "ping 3", "pong")
+
+    result, err := s.messages.SearchMessages(ctx, "app", chatNumber, model.SearchQuery{
+        Text: "ping",
+        Mode: model.SearchModeMatch,
+        Sort: model.SearchSortOldest,
+        Page: 2,
+        Size: 2,
+    })
+    if err != nil {
+        t.Fatalf("SearchMessages: %v", err)
+    }
+
+    if result.Total != 3 || result.Page != 2 || result.Size != 2 {
+        t.Errorf("total/page/size = %d/%d/%d, want 3/2/2", result.Total, result.Page, result.Size)
+    }
+    if got, want := hitBodies(result), []string{"ping 3"}; !reflect.DeepEqual(got, want) {
+        t.Fatalf("hits = %q, want %q", got, want)
+    }
+    hit := result.Hits[0]
+    if want := []string{"<em>ping</em> 3"}; !reflect.DeepEqual(hit.Highlights, want) {
+        t.Errorf("highlights = %q, want %q", hit.Highlights, want)
+    }
+    if hit.ApplicationToken != "app" || strconv.Itoa(hit.ChatNumber) != chatNumber || hit.Number != 3 {
+        t.Errorf("hit = %s chat %d message %d, want app chat %s message 3", hit.ApplicationToken, hit.ChatNumber, hit.Number, chatNumber)
+    }
+}
+
+func TestSearchMessagesFallsBackWhenIndexIsDown(t *testing.T) {
+    ctx := context.Background()
+    s := newTestServices(t)
+    chatNumber := createChatWithMessages(t, s, "app", "status report", "weekly status")
+    s.msgRepo.SetSearchAvailable(false)
+
+    result, err := s.messages.SearchMessages(ctx, "app", chatNumber, model.SearchQuery{
+        Text: "status",
+        Mode: model.SearchModeMatch,
+        Sort: model.SearchSortNewest,
+        Page: 1,
+        Size: 10,
+    })
+    if err != nil {
+        t.Fatalf("SearchMessages: %v", err)
+    }
+
+    if !result.Degraded {
+        t.Error("result is not marked degraded")
+    }
+    if got, want := hitBodies(result), []string{"weekly status", "status report"}; !reflect.DeepEqual(got, want) {
+        t.Errorf("hits = %q, want %q", got, want)
+    }
+    for _, hit := range result.Hits {
+        if len(hit.Highlights) != 0 {
+            t.Errorf("fallback hit %q has highlights %q", hit.Body, hit.Highlights)
+        }
+    }
+}
+
+func TestSearchMessagesUnknownChat(t *testing.T) {
+    s := newTestServices(t)
+
+    _, err := s.messages.SearchMessages(context.Background(), "app", "7", model.SearchQuery{
+        Text: "anything",
+        Page: 1,
+        Size: 10,
+    })
+    requireErrorKind(t, err, KindNotFound)
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+    "context"
+
+    "go.uber.org/zap"
+
+    "chat-service/internal/model"
+    "chat-service/internal/repository"
+)
+
+// publish pushes a message change to clients following the chat. Streaming
+// is best effort: the change is already committed, so a failure is only
+// logged and reconnecting clients catch up through Run's replay.
+func (s *MessageService) publish(ctx context.Context, eventType string, message *model.Message) {
+    event := &model.ChatStreamEvent{Type: eventType, Message: message}
+    if err := s.chatStream.Publish(context.WithoutCancel(ctx), message.ChatID, event); err != nil {
+        s.logger.Warn("failed to publish message to chat stream",
+            zap.Error(err),
+            zap.String("event_type", eventType),
+            zap.Uint64("message_id", message.ID))
+    }
+}
+
+// OpenStream subscribes to the changes of a chat. Errors are returned before
+// anything is streamed so the caller can still answer with a plain HTTP
+// error.
+func (s *MessageService) OpenStream(ctx context.Context, applicationToken string, chatNumber string) (*MessageStream, error) {
+    chat, err := s.getChat(ctx, applicationToken, chatNumber)
+    if err != nil {
+        return nil, err
+    }
+
+    subscription, err := s.chatStream.Subscribe(ctx, chat.ID)
+    if err != nil {
+        return nil, storageError("failed to subscribe to chat", err)
+    }
+
+    return &MessageStream{
+        chatID:       chat.ID,
+        subscription: subscription,
+        messageRepo:  s.messageRepo,
+    }, nil
+}
+
+// MessageStream delivers the changes of one chat, optionally preceded by
+// the messages a reconnecting client missed.
+type MessageStream struct {
+    chatID       uint64
+    subscription model.ChatSubscription
+    messageRepo  MessageRepository
+}
+
+// Run calls send for every event until ctx ends, send fails or the
+// subscription breaks. When after is not nil, every stored message with a
+// number greater than *after is sent first as a message_created event.
+// Since the subscription is opened before the replay starts, nothing is
+// lost in between; live creations already covered by the replay are
+// skipped. Numbers are reserved before the commit, so messages can commit
+// out of order: the replay remembers which numbers it sent rather than the
+// highest one, and a lower message committed after it still gets through.
+func (m *MessageStream) Run(ctx context.Context, after *int, send func(*model.ChatStreamEvent) error) error {
+    replayed := make(map[int]bool)
+    if after != nil {
+        page := model.PageQuery{Limit: model.MaxPageLimit, After: *after}
+        for {
+            messages, hasMore, err := m.messageRepo.ListByChat(ctx, m.chatID, page)
+            if err != nil {
+                return storageError("failed to replay messages", err)
+            }
+            for _, message := range messages {
+                if err := send(&model.ChatStreamEvent{Type: model.EventMessageCreated, Message: message}); err != nil {
+                    return err
+                }
+                replayed[message.Number] = true
+                page.After = message.Number
+            }
+            if !hasMore {
+                break
+            }
+        }
+    }
+
+    for {
+        select {
+        case <-ctx.Done():
+            return nil
+        case event, ok := <-m.subscription.Events():
+            if !ok {
+                return storageError("chat stream closed", repository.ErrUnavailable)
+            }
+            if event.Type == model.EventMessageCreated && replayed[event.Message.Number] {
+                // Each creation is published once, so the entry is done.
+                delete(replayed, event.Message.Number)
+                continue
+            }
+            if err := send(event); err != nil {
+                return err
+            }
+        }
+    }
+}
+
+func (m *MessageStream) Close() error {
+    return m.subscription.Close()
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+    "context"
+    "fmt"
+    "reflect"
+    "testing"
+
+    "chat-service/internal/model"
+)
+
+// runStream opens the stream of a chat, queues live on its subscription and
+// ends the subscription after them, then runs the stream and returns what
+// was sent as "type:number" strings.
+func runStream(t *testing.T, s *testServices, chatNumber string, after *int, live ...*model.ChatStreamEvent) []string {
+    t.Helper()
+
+    stream, err := s.messages.OpenStream(context.Background(), "app", chatNumber)
+    if err != nil {
+        t.Fatalf("OpenStream: %v", err)
+    }
+    subscription := s.stream.subscriptions[len(s.stream.subscriptions)-1]
+    subscription.deliver(live...)
+    stream.Close()
+
+    sent := []string{}
+    err = stream.Run(context.Background(), after, func(event *model.ChatStreamEvent) error {
+        sent = append(sent, fmt.Sprintf("%s:%d", event.Type, event.Message.Number))
+        return nil
+    })
+    // The subscription ending is reported as a broken stream.
+    requireErrorKind(t, err, KindUnavailable)
+
+    return sent
+}
+
+func liveEvent(eventType string, chatID uint64, number int) *model.ChatStreamEvent {
+    return &model.ChatStreamEvent{
+        Type:    eventType,
+        Message: &model.Message{ChatID: chatID, Number: number},
+    }
+}
+
+func TestMessageStreamReplaysThenFollows(t *testing.T) {
+    ctx := context.Background()
+    s := newTestServices(t)
+    chatNumber := createChatWithMessages(t, s, "app", "one", "two", "three")
+    chat, err := s.chats.GetChat(ctx, "app", chatNumber)
+    if err != nil {
+        t.Fatalf("GetChat: %v", err)
+    }
+
+    after := 1
+    sent := runStream(t, s, chatNumber, &after,
+        // Created before the replay read it: already sent by the replay.
+        liveEvent(model.EventMessageCreated, chat.ID, 3),
+        liveEvent(model.EventMessageUpdated, chat.ID, 2),
+        liveEvent(model.EventMessageCreated, chat.ID, 4),
+        liveEvent(model.EventMessageDeleted, chat.ID, 1),
+    )
+
+    want := []string{
+        "message_created:2",
+        "message_created:3",
+        "message_updated:2",
+        "message_created:4",
+        "message_deleted:1",
+    }
+    if !reflect.DeepEqual(sent, want) {
+        t.Errorf("sent %v, want %v", sent, want)
+    }
+}
+
+func TestMessageStreamDeliversOutOfOrderCommits(t *testing.T) {
+    ctx := context.Background()
+    s := newTestServices(t)
+    chatNumber := createChatWithMessages(t, s, "app")
+    chat, err := s.chats.GetChat(ctx, "app", chatNumber)
+    if err != nil {
+        t.Fatalf("GetChat: %v", err)
+    }
+
+    // Message 4 got its number before message 5 but commits after the
+    // replay has read 5.
+    for _, number := range []int{1, 2, 3, 5} {
+        if err := s.msgRepo.Create(ctx, chat, &model.Message{ChatID: chat.ID, Number: number}); err != nil {
+            t.Fatalf("Create(%d): %v", number, err)
+        }
+    }
+
+    after := 0
+    sent := runStream(t, s, chatNumber, &after,
+        liveEvent(model.EventMessageCreated, chat.ID, 5),
+        liveEvent(model.EventMessageCreated, chat.ID, 4),
+        liveEvent(model.EventMessageCreated, chat.ID, 6),
+    )
+
+    want := []string{
+        "message_created:1",
+        "message_created:2",
+        "message_created:3",
+        "message_created:5",
+        "message_created:4",
+        "message_created:6",
+    }
+    if !reflect.DeepEqual(sent, want) {
+        t.Errorf("sent %v, want %v", sent, want)
+    }
+}
+
+func TestMessageStreamWithoutReplay(t *testing.T) {
+    ctx := context.Background()
+    s := newTestServices(t)
+    chatNumber := createChatWithMessages(t, s, "app", "one", "two")
+    chat, err := s.chats.GetChat(ctx, "app", chatNumber)
+    if err != nil {
+        t.Fatalf("GetChat: %v", err)
+    }
+
+    sent := runStream(t, s, chatNumber, nil,
+        liveEvent(model.EventMessageCreated, chat.ID, 3),
+    )
+
+    if want := []string{"message_created:3"}; !reflect.DeepEqual(sent, want) {
+        t.Errorf("sent %v, want %v", sent, want)
+    }
+}
+
+func TestOpenStreamUnknownChat(t *testing.T) {
+    s := newTestServices(t)
+
+    _, err := s.messages.OpenStream(context.Background(), "app", "9")
+    requireErrorKind(t, err, KindNotFound)
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "time"
+
+    "go.uber.org/zap"
+
+    "chat-service/internal/model"
+    "chat-service/internal/repository/mysql"
+    "chat-service/pkg/events"
+)
+
+const (
+    outboxMinBackoff = time.Second
+    outboxMaxBackoff = 5 * time.Minute
+)
+
+type OutboxRelayConfig struct {
+    PollInterval time.Duration
+    BatchSize    int
+    Retention    time.Duration
+}
+
+// OutboxRelay publishes events written to the outbox table by the
+// repositories, retrying with exponential backoff until the event bus
+// accepts them.
+type OutboxRelay struct {
+    outboxRepo *mysql.OutboxRepository
+    publisher  EventPublisher
+    cfg        OutboxRelayConfig
+    logger     *zap.Logger
+}
+
+func NewOutboxRelay(
+    outboxRepo *mysql.OutboxRepository,
+    publisher EventPublisher,
+    cfg OutboxRelayConfig,
+    logger *zap.Logger,
+) *OutboxRelay {
+    if cfg.PollInterval <= 0 {
+        cfg.PollInterval = time.Second
+    }
+    if cfg.BatchSize <= 0 {
+        cfg.BatchSize = 100
+    }
+    if cfg.Retention <= 0 {
+        cfg.Retention = 24 * time.Hour
+    }
+
+    return &OutboxRelay{
+        outboxRepo: outboxRepo,
+        publisher:  publisher,
+        cfg:        cfg,
+        logger:     logger,
+    }
+}
+
+// Run polls the outbox until ctx is cancelled. A full batch is followed
+// immediately by another poll so a backlog drains without waiting.
+func (r *OutboxRelay) Run(ctx context.Context) {
+    ticker := time.NewTicker(r.cfg.PollInterval)
+    defer ticker.Stop()
+
+    cleanup := time.NewTicker(time.Hour)
+    defer cleanup.Stop()
+
+    for {
+        // Once the event bus turns out to be unavailable, the rest of the batch
+        // is failed right away instead of each event waiting out the
+        // publish timeout while the batch's rows stay locked.
+        var unavailable error
+        published, err := r.outboxRepo.ProcessPending(ctx, r.cfg.BatchSize, func(event *model.OutboxEvent) error {
+            if unavailable != nil {
+                return unavailable
+            }
+            err := r.publish(ctx, event)
+            if errors.Is(err, events.ErrUnavailable) {
+                unavailable = err
+            }
+            return err
+        }, outboxBackoff)
+        if err != nil && ctx.Err() == nil {
+            r.logger.Error("failed to relay outbox events", zap.Error(err))
+        }
+        if published == r.cfg.BatchSize {
+            continue
+        }
+
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        case <-cleanup.C:
+            deleted, err := r.outboxRepo.DeletePublishedBefore(ctx, time.Now().UTC().Add(-r.cfg.Retention))
+            if err != nil {
+                r.logger.Error("failed to prune outbox events", zap.Error(err))
+            } else if deleted > 0 {
+                r.logger.Info("pruned outbox events", zap.Int64("deleted", deleted))
+            }
+        }
+    }
+}
+
+func (r *OutboxRelay) publish(ctx context.Context, event *model.OutboxEvent) error {
+    envelope, err := outboxEnvelope(event)
+    if err == nil {
+        err = r.publisher.Publish(ctx, envelope)
+    }
+
+    if err != nil {
+        r.logger.Warn("failed to publish outbox event",
+            zap.Error(err),
+            zap.Uint64("event_id", event.ID),
+            zap.String("event_type", event.EventType),
+            zap.Int("attempts", event.Attempts+1))
+    }
+
+    return err
+}
+
+// outboxEnvelope returns the envelope stored in an outbox row. Rows written
+// before envelopes were introduced hold the bare payload; they are wrapped
+// with an id derived from the row, which stays stable across retries.
+func outboxEnvelope(event *model.OutboxEvent) (*events.Envelope, error) {
+    envelope, err := events.Parse(event.Payload)
+    if !errors.Is(err, events.ErrNotEnvelope) {
+        return envelope, err
+    }
+
+    // Only chat payloads name their application; for messages the token
+    // stays empty.
+    var owner events.Chat
+    if event.EventType == model.EventChatCreated {
+        if err := json.Unmarshal(event.Payload, &owner); err != nil {
+            return nil, fmt.Errorf("failed to unmarshal chat payload: %w", err)
+        }
+    }
+
+    envelope, err = events.New(event.EventType, owner.ApplicationID, event.CreatedAt, json.RawMessage(event.Payload))
+    if err != nil {
+        return nil, err
+    }
+    envelope.ID = fmt.Sprintf("outbox-%d", event.ID)
+    return envelope, nil
+}
+
+func outboxBackoff(attempts int) time.Duration {
+    backoff := outboxMinBackoff
+    for i := 1; i < attempts && backoff < outboxMaxBackoff; i++ {
+        backoff *= 2
+    }
+    if backoff > outboxMaxBackoff {
+        backoff = outboxMaxBackoff
+    }
+    return backoff
+}
@@ -0,0 +1,27 @@
+package service
+
+import (
+    "chat-service/internal/model"
+)
+
+// pageCursors derives the cursors of a non-empty page whose first and last
+// rows carry the given numbers. hasMore refers to the direction the page was
+// fetched in; the opposite direction has more rows whenever the request
+// itself started from a cursor.
+func pageCursors(page model.PageQuery, first int, last int, hasMore bool) (next string, prev string) {
+    if page.Before > 0 {
+        next = model.EncodeCursor(last)
+        if hasMore {
+            prev = model.EncodeCursor(first)
+        }
+        return next, prev
+    }
+
+    if hasMore {
+        next = model.EncodeCursor(last)
+    }
+    if page.After > 0 {
+        prev = model.EncodeCursor(first)
+    }
+    return next, prev
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+    "context"
+
+    "chat-service/internal/model"
+)
+
+// The storage the services depend on. The MySQL, Redis and Elasticsearch
+// repositories implement these in production and the memory package in
+// tests. Implementations wrap failures with the repository errors, so
+// repository.ErrDuplicate and repository.ErrUnavailable mean the same thing
+// whichever backend returned them.
+
+// ChatRepository stores chats, unique by application and number. Create
+// fails with repository.ErrDuplicate when the number is taken, and
+// GetByNumber returns nil when no such chat exists.
+type ChatRepository interface {
+    Create(ctx context.Context, chat *model.Chat) error
+    GetByNumber(ctx context.Context, applicationID string, number int) (*model.Chat, error)
+    ListByApplication(ctx context.Context, applicationToken string, page model.PageQuery) ([]*model.Chat, bool, error)
+}
+
+// MessageRepository stores messages, unique by chat and number. Creates
+// fail with repository.ErrDuplicate when a number is taken, CreateBatch
+// storing none of the messages then. Lookups, updates and deletes return
+// nil when no such message exists.
+type MessageRepository interface {
+    Create(ctx context.Context, chat *model.Chat, message *model.Message) error
+    CreateBatch(ctx context.Context, chat *model.Chat, messages []*model.Message) error
+    GetByNumber(ctx context.Context, chatID uint64, number int) (*model.Message, error)
+    Update(ctx context.Context, chat *model.Chat, number int, body string) (*model.Message, error)
+    Delete(ctx context.Context, chat *model.Chat, number int) (*model.Message, error)
+    ListByChat(ctx context.Context, chatID uint64, page model.PageQuery) ([]*model.Message, bool, error)
+}
+
+// SequenceRepository allocates chat and message numbers. Numbers only grow;
+// the resync methods raise a counter to the highest number already stored.
+type SequenceRepository interface {
+    NextChatNumber(ctx context.Context, applicationID string) (int, error)
+    NextMessageNumber(ctx context.Context, chatID uint64) (int, error)
+    ReserveMessageNumbers(ctx context.Context, chatID uint64, count int) (int, error)
+    ResyncChatNumber(ctx context.Context, applicationID string) error
+    ResyncMessageNumber(ctx context.Context, chatID uint64) error
+}
+
+// SearchRepository searches message bodies. Search fails with
+// repository.ErrUnavailable while the search index is down, and
+// SearchFullText is the fallback used then.
+type SearchRepository interface {
+    Search(ctx context.Context, scope model.SearchScope, search model.SearchQuery) (*model.SearchResult, error)
+    SearchFullText(ctx context.Context, scope model.SearchScope, search model.SearchQuery) (*model.SearchResult, error)
+}
+
+// ChatStream carries message changes to clients following a chat.
+type ChatStream interface {
+    Publish(ctx context.Context, chatID uint64, event *model.ChatStreamEvent) error
+    Subscribe(ctx context.Context, chatID uint64) (model.ChatSubscription, error)
+}
+
+// ApplicationEventLog records the activity feed of applications.
+type ApplicationEventLog interface {
+    Append(ctx context.Context, applicationToken string, eventType string, payload interface{}) error
+}
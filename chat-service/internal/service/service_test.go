@@ -0,0 +1,162 @@
+package service
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "sync"
+    "testing"
+
+    "go.uber.org/zap"
+
+    "chat-service/internal/model"
+    "chat-service/internal/repository"
+    "chat-service/internal/repository/memory"
+    "chat-service/internal/repository/mysql"
+    "chat-service/internal/repository/redis"
+)
+
+// The production repositories and the memory ones must both satisfy the
+// interfaces the services take.
+var (
+    _ ChatRepository      = (*mysql.ChatRepository)(nil)
+    _ MessageRepository   = (*mysql.MessageRepository)(nil)
+    _ SearchRepository    = (*mysql.MessageRepository)(nil)
+    _ SequenceRepository  = (*redis.SequenceRepository)(nil)
+    _ ChatStream          = (*redis.ChatStream)(nil)
+    _ ApplicationEventLog = (*redis.ApplicationEvents)(nil)
+
+    _ ChatRepository     = (*memory.ChatRepository)(nil)
+    _ MessageRepository  = (*memory.MessageRepository)(nil)
+    _ SearchRepository   = (*memory.MessageRepository)(nil)
+    _ SequenceRepository = (*memory.SequenceRepository)(nil)
+)
+
+// testServices wires the chat and message services to memory storage.
+type testServices struct {
+    chats     *ChatService
+    messages  *MessageService
+    chatRepo  *memory.ChatRepository
+    msgRepo   *memory.MessageRepository
+    sequences *memory.SequenceRepository
+    stream    *recordingStream
+    eventLog  *recordingEventLog
+}
+
+func newTestServices(t *testing.T) *testServices {
+    t.Helper()
+
+    chatRepo := memory.NewChatRepository()
+    msgRepo := memory.NewMessageRepository()
+    sequences := memory.NewSequenceRepository(memory.NewSequenceSource(chatRepo, msgRepo))
+    stream := &recordingStream{}
+    eventLog := &recordingEventLog{}
+    logger := zap.NewNop()
+
+    return &testServices{
+        chats:     NewChatService(chatRepo, sequences, eventLog, logger),
+        messages:  NewMessageService(msgRepo, msgRepo, chatRepo, sequences, stream, eventLog, logger),
+        chatRepo:  chatRepo,
+        msgRepo:   msgRepo,
+        sequences: sequences,
+        stream:    stream,
+        eventLog:  eventLog,
+    }
+}
+
+// recordingStream remembers published chat stream events and hands out
+// subscriptions whose events the test supplies.
+type recordingStream struct {
+    mu            sync.Mutex
+    events        []*model.ChatStreamEvent
+    subscriptions []*fakeSubscription
+}
+
+func (s *recordingStream) Publish(ctx context.Context, chatID uint64, event *model.ChatStreamEvent) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.events = append(s.events, event)
+    return nil
+}
+
+func (s *recordingStream) Subscribe(ctx context.Context, chatID uint64) (model.ChatSubscription, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    subscription := &fakeSubscription{events: make(chan *model.ChatStreamEvent, 100)}
+    s.subscriptions = append(s.subscriptions, subscription)
+    return subscription, nil
+}
+
+// fakeSubscription delivers the events queued with deliver. Closing it ends
+// the events once the queued ones are read, like a dropped connection.
+type fakeSubscription struct {
+    events chan *model.ChatStreamEvent
+    once   sync.Once
+}
+
+func (s *fakeSubscription) deliver(events ...*model.ChatStreamEvent) {
+    for _, event := range events {
+        s.events <- event
+    }
+}
+
+func (s *fakeSubscription) Events() <-chan *model.ChatStreamEvent {
+    return s.events
+}
+
+func (s *fakeSubscription) Close() error {
+    s.once.Do(func() { close(s.events) })
+    return nil
+}
+
+type recordedEvent struct {
+    applicationToken string
+    eventType        string
+    payload          interface{}
+}
+
+// recordingEventLog remembers appended application events.
+type recordingEventLog struct {
+    mu     sync.Mutex
+    events []recordedEvent
+}
+
+func (l *recordingEventLog) Append(ctx context.Context, applicationToken string, eventType string, payload interface{}) error {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    l.events = append(l.events, recordedEvent{applicationToken, eventType, payload})
+    return nil
+}
+
+// requireErrorKind fails the test unless err is a service Error of kind.
+func requireErrorKind(t *testing.T, err error, kind ErrorKind) {
+    t.Helper()
+
+    var serviceErr *Error
+    if !errors.As(err, &serviceErr) {
+        t.Fatalf("err = %v, want a service error of kind %s", err, kind)
+    }
+    if serviceErr.Kind != kind {
+        t.Fatalf("err kind = %s, want %s (%v)", serviceErr.Kind, kind, err)
+    }
+}
+
+func TestMayHaveCommitted(t *testing.T) {
+    commitErr := fmt.Errorf("failed to commit message: %w: %w", repository.ErrCommitUnknown, repository.ErrUnavailable)
+
+    for _, tc := range []struct {
+        err  error
+        want bool
+    }{
+        {storageError("failed to create message", commitErr), true},
+        {storageError("failed to reserve message number", repository.ErrUnavailable), false},
+        {ErrChatNotFound, false},
+    } {
+        if got := MayHaveCommitted(tc.err); got != tc.want {
+            t.Errorf("MayHaveCommitted(%v) = %v, want %v", tc.err, got, tc.want)
+        }
+    }
+}
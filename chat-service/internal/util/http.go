@@ -3,10 +3,12 @@ package util
 import (
     "encoding/json"
     "net/http"
+
+    "chat-service/internal/model"
 )
 
-func RespondWithError(w http.ResponseWriter, code int, message string) {
-    RespondWithJSON(w, code, map[string]string{"error": message})
+func RespondWithError(w http.ResponseWriter, status int, code string, message string) {
+    RespondWithJSON(w, status, model.ErrorResponse{Error: message, Code: code})
 }
 
 func RespondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
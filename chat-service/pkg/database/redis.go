@@ -9,12 +9,15 @@ import (
 type RedisConfig struct {
     Host string
     Port string
+    // PoolSize caps the open connections; 0 keeps the go-redis default.
+    PoolSize int
 }
 
 func NewRedisConnection(cfg RedisConfig) (*redis.Client, error) {
     addr := fmt.Sprintf("%s:%s", cfg.Host, cfg.Port)
     client := redis.NewClient(&redis.Options{
-        Addr: addr,
+        Addr:     addr,
+        PoolSize: cfg.PoolSize,
     })
 
     ctx := context.Background()
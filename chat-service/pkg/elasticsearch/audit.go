@@ -0,0 +1,170 @@
+package elasticsearch
+
+import (
+    "encoding/json"
+    "fmt"
+    "strconv"
+)
+
+const auditPageSize = 1000
+
+// ChatStats summarizes the message documents of one chat.
+type ChatStats struct {
+    Count     int64
+    MaxNumber int
+    NumberSum int64
+}
+
+// MessageStatsByChat returns the document count and the highest and summed
+// message numbers of every chat in index, paging through a composite
+// aggregation so the number of chats is not bounded by a bucket limit.
+func (c *Client) MessageStatsByChat(index string) (map[uint64]ChatStats, error) {
+    stats := make(map[uint64]ChatStats)
+
+    var after map[string]json.RawMessage
+    for {
+        composite := map[string]interface{}{
+            "size": auditPageSize,
+            "sources": []map[string]interface{}{
+                {"chat_id": map[string]interface{}{"terms": map[string]interface{}{"field": "chat_id"}}},
+            },
+        }
+        if after != nil {
+            composite["after"] = after
+        }
+
+        query := map[string]interface{}{
+            "size": 0,
+            "aggs": map[string]interface{}{
+                "chats": map[string]interface{}{
+                    "composite": composite,
+                    "aggs": map[string]interface{}{
+                        "max_number": map[string]interface{}{"max": map[string]interface{}{"field": "number"}},
+                        "number_sum": map[string]interface{}{"sum": map[string]interface{}{"field": "number"}},
+                    },
+                },
+            },
+        }
+
+        raw, err := c.Search(index, query)
+        if err != nil {
+            return nil, err
+        }
+
+        var response struct {
+            Aggregations struct {
+                Chats struct {
+                    AfterKey map[string]json.RawMessage `json:"after_key"`
+                    Buckets  []struct {
+                        Key struct {
+                            ChatID uint64 `json:"chat_id"`
+                        } `json:"key"`
+                        DocCount  int64 `json:"doc_count"`
+                        MaxNumber struct {
+                            Value float64 `json:"value"`
+                        } `json:"max_number"`
+                        NumberSum struct {
+                            Value float64 `json:"value"`
+                        } `json:"number_sum"`
+                    } `json:"buckets"`
+                } `json:"chats"`
+            } `json:"aggregations"`
+        }
+        if err := json.Unmarshal(raw, &response); err != nil {
+            return nil, fmt.Errorf("failed to parse chat stats: %w", err)
+        }
+
+        for _, bucket := range response.Aggregations.Chats.Buckets {
+            stats[bucket.Key.ChatID] = ChatStats{
+                Count:     bucket.DocCount,
+                MaxNumber: int(bucket.MaxNumber.Value),
+                NumberSum: int64(bucket.NumberSum.Value),
+            }
+        }
+
+        if len(response.Aggregations.Chats.Buckets) < auditPageSize || response.Aggregations.Chats.AfterKey == nil {
+            return stats, nil
+        }
+        after = response.Aggregations.Chats.AfterKey
+    }
+}
+
+// MessageIDsOfChat returns the ids of every message document of a chat in
+// ascending order.
+func (c *Client) MessageIDsOfChat(index string, chatID uint64) ([]uint64, error) {
+    var ids []uint64
+
+    var after []json.RawMessage
+    for {
+        query := map[string]interface{}{
+            "size":    auditPageSize,
+            "_source": false,
+            "query": map[string]interface{}{
+                "term": map[string]interface{}{"chat_id": chatID},
+            },
+            "sort": []map[string]interface{}{
+                {"id": map[string]interface{}{"order": "asc"}},
+            },
+        }
+        if after != nil {
+            query["search_after"] = after
+        }
+
+        raw, err := c.Search(index, query)
+        if err != nil {
+            return nil, err
+        }
+
+        var response struct {
+            Hits struct {
+                Hits []struct {
+                    ID   string            `json:"_id"`
+                    Sort []json.RawMessage `json:"sort"`
+                } `json:"hits"`
+            } `json:"hits"`
+        }
+        if err := json.Unmarshal(raw, &response); err != nil {
+            return nil, fmt.Errorf("failed to parse message ids: %w", err)
+        }
+
+        for _, hit := range response.Hits.Hits {
+            id, err := strconv.ParseUint(hit.ID, 10, 64)
+            if err != nil {
+                return nil, fmt.Errorf("unexpected document id %q: %w", hit.ID, err)
+            }
+            ids = append(ids, id)
+        }
+
+        if len(response.Hits.Hits) < auditPageSize {
+            return ids, nil
+        }
+        after = response.Hits.Hits[len(response.Hits.Hits)-1].Sort
+    }
+}
+
+// BulkDelete removes documents by id in a single Bulk API request. Ids that
+// do not exist are ignored.
+func (c *Client) BulkDelete(index string, ids []string) error {
+    if len(ids) == 0 {
+        return nil
+    }
+
+    operations := make([]*bulkOperation, len(ids))
+    for i, id := range ids {
+        operations[i] = &bulkOperation{action: "delete", index: index, id: id}
+    }
+
+    failures, err := c.bulk(operations)
+    if err != nil {
+        return err
+    }
+    if len(failures) == 0 {
+        return nil
+    }
+
+    failed := make(map[string]interface{}, len(failures))
+    for _, failure := range failures {
+        failed[failure.operation.id] = failure.reason
+    }
+    return fmt.Errorf("failed to delete %d of %d documents: %v", len(failed), len(ids), failed)
+}
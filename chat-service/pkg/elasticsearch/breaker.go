@@ -0,0 +1,80 @@
+package elasticsearch
+
+import (
+    "errors"
+    "fmt"
+    "sync"
+    "time"
+)
+
+// ErrCircuitOpen is returned without contacting the cluster while the
+// circuit breaker is open. It wraps ErrUnavailable.
+var ErrCircuitOpen = fmt.Errorf("%w: circuit breaker open", ErrUnavailable)
+
+// breaker is a circuit breaker that opens after threshold consecutive
+// failures caused by the cluster being unavailable. While open, calls fail
+// fast for cooldown; after that one trial call is let through, which closes
+// the breaker again if it succeeds and re-opens it otherwise.
+type breaker struct {
+    threshold int
+    cooldown  time.Duration
+
+    mu        sync.Mutex
+    failures  int
+    openUntil time.Time
+    trial     bool
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+    return &breaker{threshold: threshold, cooldown: cooldown}
+}
+
+// do runs call unless the breaker is open, and records its outcome. Errors
+// that do not stem from unavailability, such as a malformed query, count as
+// successes: the cluster answered.
+func (b *breaker) do(call func() error) error {
+    if !b.allow() {
+        return ErrCircuitOpen
+    }
+
+    err := call()
+    b.record(err == nil || !errors.Is(err, ErrUnavailable))
+    return err
+}
+
+func (b *breaker) allow() bool {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    if b.openUntil.IsZero() {
+        return true
+    }
+    if time.Now().Before(b.openUntil) || b.trial {
+        return false
+    }
+    b.trial = true
+    return true
+}
+
+func (b *breaker) record(success bool) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    b.trial = false
+    if success {
+        if !b.openUntil.IsZero() {
+            fmt.Println("Elasticsearch circuit breaker closed")
+        }
+        b.failures = 0
+        b.openUntil = time.Time{}
+        return
+    }
+
+    b.failures++
+    if b.failures >= b.threshold {
+        if b.openUntil.IsZero() {
+            fmt.Printf("Elasticsearch circuit breaker opened after %d failures\n", b.failures)
+        }
+        b.openUntil = time.Now().Add(b.cooldown)
+    }
+}
@@ -0,0 +1,269 @@
+package elasticsearch
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "sync"
+    "time"
+)
+
+// ErrIndexerClosed is returned when a document is queued after Close.
+var ErrIndexerClosed = errors.New("bulk indexer closed")
+
+type BulkIndexerConfig struct {
+    // BatchSize is the number of operations sent per Bulk API request.
+    BatchSize int
+    // FlushInterval bounds how long a queued operation waits for a batch
+    // to fill up.
+    FlushInterval time.Duration
+    // QueueSize is the number of operations that may wait to be sent.
+    // Once it is reached, Index and Delete block until there is room.
+    QueueSize int
+    // MaxRetries is how many times items rejected with a retryable status
+    // are sent again before they are dropped.
+    MaxRetries int
+    // RetryBackoff is the delay before the first retry; it doubles on every
+    // further attempt.
+    RetryBackoff time.Duration
+}
+
+// BulkIndexer batches index and delete operations in the background and
+// sends them with the Bulk API, so writers never wait for Elasticsearch and
+// the cluster sees a few large requests instead of one per document.
+// Operations are sent in the order they were queued.
+type BulkIndexer struct {
+    client *Client
+    cfg    BulkIndexerConfig
+    queue  chan *bulkOperation
+
+    mu     sync.RWMutex
+    closed bool
+    done   chan struct{}
+}
+
+func NewBulkIndexer(client *Client, cfg BulkIndexerConfig) *BulkIndexer {
+    if cfg.BatchSize <= 0 {
+        cfg.BatchSize = 500
+    }
+    if cfg.FlushInterval <= 0 {
+        cfg.FlushInterval = time.Second
+    }
+    if cfg.QueueSize <= 0 {
+        cfg.QueueSize = 10 * cfg.BatchSize
+    }
+    if cfg.RetryBackoff <= 0 {
+        cfg.RetryBackoff = 100 * time.Millisecond
+    }
+
+    indexer := &BulkIndexer{
+        client: client,
+        cfg:    cfg,
+        queue:  make(chan *bulkOperation, cfg.QueueSize),
+        done:   make(chan struct{}),
+    }
+    go indexer.run()
+    return indexer
+}
+
+// Index queues document to be indexed under id. It blocks while the queue is
+// full and fails only if ctx ends first or the indexer is closed.
+func (b *BulkIndexer) Index(ctx context.Context, index string, id string, document interface{}) error {
+    operation, err := newIndexOperation(index, id, document)
+    if err != nil {
+        return err
+    }
+    return b.enqueue(ctx, operation)
+}
+
+// Delete queues the removal of document id. Deleting a document that does
+// not exist is not an error.
+func (b *BulkIndexer) Delete(ctx context.Context, index string, id string) error {
+    return b.enqueue(ctx, &bulkOperation{action: "delete", index: index, id: id})
+}
+
+func (b *BulkIndexer) enqueue(ctx context.Context, operation *bulkOperation) error {
+    b.mu.RLock()
+    defer b.mu.RUnlock()
+
+    if b.closed {
+        return ErrIndexerClosed
+    }
+
+    select {
+    case b.queue <- operation:
+        return nil
+    case <-ctx.Done():
+        return fmt.Errorf("bulk indexer queue is full: %w: %w", ErrUnavailable, ctx.Err())
+    }
+}
+
+// Close stops accepting operations and waits until everything queued so far
+// has been sent, or until ctx ends.
+func (b *BulkIndexer) Close(ctx context.Context) error {
+    b.mu.Lock()
+    if !b.closed {
+        b.closed = true
+        close(b.queue)
+    }
+    b.mu.Unlock()
+
+    select {
+    case <-b.done:
+        return nil
+    case <-ctx.Done():
+        return fmt.Errorf("bulk indexer did not flush in time: %w", ctx.Err())
+    }
+}
+
+func (b *BulkIndexer) run() {
+    defer close(b.done)
+
+    ticker := time.NewTicker(b.cfg.FlushInterval)
+    defer ticker.Stop()
+
+    batch := make([]*bulkOperation, 0, b.cfg.BatchSize)
+    for {
+        select {
+        case operation, ok := <-b.queue:
+            if !ok {
+                b.flush(batch)
+                return
+            }
+            batch = append(batch, operation)
+            if len(batch) >= b.cfg.BatchSize {
+                b.flush(batch)
+                batch = batch[:0]
+            }
+        case <-ticker.C:
+            if len(batch) > 0 {
+                b.flush(batch)
+                batch = batch[:0]
+            }
+        }
+    }
+}
+
+// flush sends batch, retrying the items that failed with a retryable status
+// before moving on so later operations on the same document cannot overtake
+// them.
+func (b *BulkIndexer) flush(batch []*bulkOperation) {
+    pending := batch
+    backoff := b.cfg.RetryBackoff
+
+    for attempt := 0; len(pending) > 0; attempt++ {
+        failures, err := b.client.bulk(pending)
+        if err != nil {
+            if attempt >= b.cfg.MaxRetries {
+                fmt.Printf("Dropping %d bulk operations after %d attempts: %v\n", len(pending), attempt+1, err)
+                return
+            }
+            fmt.Printf("Bulk request failed, retrying in %s: %v\n", backoff, err)
+            time.Sleep(backoff)
+            backoff *= 2
+            continue
+        }
+
+        var retry []*bulkOperation
+        for _, failure := range failures {
+            if isUnavailableStatus(failure.status) && attempt < b.cfg.MaxRetries {
+                retry = append(retry, failure.operation)
+                continue
+            }
+            fmt.Printf("Failed to %s document %s in %s: %v\n",
+                failure.operation.action, failure.operation.id, failure.operation.index, failure.reason)
+        }
+
+        pending = retry
+        if len(pending) > 0 {
+            time.Sleep(backoff)
+            backoff *= 2
+        }
+    }
+}
+
+type bulkOperation struct {
+    action   string
+    index    string
+    id       string
+    document json.RawMessage
+}
+
+// newIndexOperation encodes document right away so later changes to it do
+// not leak into the queued operation.
+func newIndexOperation(index string, id string, document interface{}) (*bulkOperation, error) {
+    encoded, err := json.Marshal(document)
+    if err != nil {
+        return nil, fmt.Errorf("failed to encode document: %w", err)
+    }
+    return &bulkOperation{action: "index", index: index, id: id, document: encoded}, nil
+}
+
+type bulkFailure struct {
+    operation *bulkOperation
+    status    int
+    reason    interface{}
+}
+
+// bulk sends operations in one Bulk API request and returns the items that
+// failed. A missing document on delete is not a failure.
+func (c *Client) bulk(operations []*bulkOperation) ([]bulkFailure, error) {
+    var buf bytes.Buffer
+    encoder := json.NewEncoder(&buf)
+    for _, operation := range operations {
+        action := map[string]interface{}{
+            operation.action: map[string]interface{}{
+                "_index": operation.index,
+                "_id":    operation.id,
+            },
+        }
+        if err := encoder.Encode(action); err != nil {
+            return nil, fmt.Errorf("failed to encode bulk action: %w", err)
+        }
+        if operation.action == "index" {
+            buf.Write(operation.document)
+            buf.WriteByte('\n')
+        }
+    }
+
+    res, err := c.es.Bulk(&buf)
+    if err != nil {
+        return nil, fmt.Errorf("failed to execute bulk request: %w: %w", ErrUnavailable, err)
+    }
+    defer res.Body.Close()
+
+    if res.IsError() {
+        return nil, responseError(res, "bulk request failed")
+    }
+
+    var bulkResponse struct {
+        Errors bool `json:"errors"`
+        Items  []map[string]struct {
+            Status int                    `json:"status"`
+            Error  map[string]interface{} `json:"error"`
+        } `json:"items"`
+    }
+    if err := json.NewDecoder(res.Body).Decode(&bulkResponse); err != nil {
+        return nil, fmt.Errorf("failed to decode bulk response: %w", err)
+    }
+    if !bulkResponse.Errors {
+        return nil, nil
+    }
+
+    var failures []bulkFailure
+    for i, item := range bulkResponse.Items {
+        for action, result := range item {
+            if result.Error == nil || (action == "delete" && result.Status == 404) {
+                continue
+            }
+            failures = append(failures, bulkFailure{
+                operation: operations[i],
+                status:    result.Status,
+                reason:    result.Error["reason"],
+            })
+        }
+    }
+    return failures, nil
+}
@@ -3,19 +3,29 @@ package elasticsearch
 import (
     "bytes"
     "encoding/json"
+    "errors"
     "fmt"
     "time"
     "github.com/elastic/go-elasticsearch/v8"
 )
 
+// ErrUnavailable is wrapped into errors caused by the cluster being
+// unreachable or overloaded, as opposed to a rejected request.
+var ErrUnavailable = errors.New("elasticsearch unavailable")
+
 type Config struct {
     URL        string
     MaxRetries int
     RetryDelay time.Duration
+    // BreakerThreshold consecutive unavailable errors open the circuit
+    // breaker around searches for BreakerCooldown.
+    BreakerThreshold int
+    BreakerCooldown  time.Duration
 }
 
 type Client struct {
-    es *elasticsearch.Client
+    es      *elasticsearch.Client
+    breaker *breaker
 }
 
 func NewClient(cfg Config) (*Client, error) {
@@ -25,6 +35,12 @@ func NewClient(cfg Config) (*Client, error) {
     if cfg.RetryDelay == 0 {
         cfg.RetryDelay = 5 * time.Second // default delay
     }
+    if cfg.BreakerThreshold == 0 {
+        cfg.BreakerThreshold = 5
+    }
+    if cfg.BreakerCooldown == 0 {
+        cfg.BreakerCooldown = 30 * time.Second
+    }
 
     config := elasticsearch.Config{
         Addresses: []string{cfg.URL},
@@ -62,41 +78,66 @@ func NewClient(cfg Config) (*Client, error) {
         }
 
         fmt.Println("Successfully connected to Elasticsearch!")
-        return &Client{es: client}, nil
+
+        c := &Client{
+            es:      client,
+            breaker: newBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown),
+        }
+        if err := c.EnsureMessagesIndex(); err != nil {
+            return nil, fmt.Errorf("failed to bootstrap messages index: %w", err)
+        }
+        return c, nil
     }
 
     return nil, fmt.Errorf("could not connect to Elasticsearch after %d attempts: %w", cfg.MaxRetries, err)
 }
 
-func (c *Client) Index(index string, id string, document interface{}) error {
-    var buf bytes.Buffer
-    if err := json.NewEncoder(&buf).Encode(document); err != nil {
-        return fmt.Errorf("failed to encode document: %w", err)
+// BulkLoad indexes many documents in a single Bulk API request without
+// waiting for a refresh, for loading data that does not need to be
+// searchable right away. documents maps document ids to their bodies.
+// Per-item failures are reported together in the returned error.
+func (c *Client) BulkLoad(index string, documents map[string]interface{}) error {
+    if len(documents) == 0 {
+        return nil
     }
 
-    res, err := c.es.Index(
-        index,
-        &buf,
-        c.es.Index.WithDocumentID(id),
-        c.es.Index.WithRefresh("true"), 
-    )
-    if err != nil {
-        return fmt.Errorf("failed to index document: %w", err)
+    operations := make([]*bulkOperation, 0, len(documents))
+    for id, document := range documents {
+        operation, err := newIndexOperation(index, id, document)
+        if err != nil {
+            return err
+        }
+        operations = append(operations, operation)
     }
-    defer res.Body.Close()
 
-    if res.IsError() {
-        var errorMap map[string]interface{}
-        if err := json.NewDecoder(res.Body).Decode(&errorMap); err != nil {
-            return fmt.Errorf("failed to decode error response: %w", err)
-        }
-        return fmt.Errorf("failed to index document: %v", errorMap)
+    failures, err := c.bulk(operations)
+    if err != nil {
+        return err
+    }
+    if len(failures) == 0 {
+        return nil
     }
 
-    return nil
+    failed := make(map[string]interface{}, len(failures))
+    for _, failure := range failures {
+        failed[failure.operation.id] = failure.reason
+    }
+    return fmt.Errorf("failed to index %d of %d documents: %v", len(failed), len(documents), failed)
 }
 
+// Search runs query against index. Calls fail fast with ErrCircuitOpen while
+// the cluster is considered down.
 func (c *Client) Search(index string, query map[string]interface{}) ([]byte, error) {
+    var body []byte
+    err := c.breaker.do(func() error {
+        var err error
+        body, err = c.search(index, query)
+        return err
+    })
+    return body, err
+}
+
+func (c *Client) search(index string, query map[string]interface{}) ([]byte, error) {
     var buf bytes.Buffer
     if err := json.NewEncoder(&buf).Encode(query); err != nil {
         return nil, fmt.Errorf("failed to encode query: %w", err)
@@ -105,12 +146,11 @@ func (c *Client) Search(index string, query map[string]interface{}) ([]byte, err
     res, err := c.es.Search(
         c.es.Search.WithIndex(index),
         c.es.Search.WithBody(&buf),
-        c.es.Search.WithPretty(),
-        c.es.Search.WithSize(10),
+        c.es.Search.WithTrackTotalHits(true),
         c.es.Search.WithTimeout(30 * time.Second),
     )
     if err != nil {
-        return nil, fmt.Errorf("failed to execute search: %w", err)
+        return nil, fmt.Errorf("failed to execute search: %w: %w", ErrUnavailable, err)
     }
     defer res.Body.Close()
 
@@ -119,6 +159,9 @@ func (c *Client) Search(index string, query map[string]interface{}) ([]byte, err
         if err := json.NewDecoder(res.Body).Decode(&errorMap); err != nil {
             return nil, fmt.Errorf("failed to decode error response: %w", err)
         }
+        if isUnavailableStatus(res.StatusCode) {
+            return nil, fmt.Errorf("search failed: %w: %v", ErrUnavailable, errorMap)
+        }
         return nil, fmt.Errorf("search failed: %v", errorMap)
     }
 
@@ -129,3 +172,7 @@ func (c *Client) Search(index string, query map[string]interface{}) ([]byte, err
 
     return buf2.Bytes(), nil
 }
+
+func isUnavailableStatus(status int) bool {
+    return status == 429 || status == 502 || status == 503 || status == 504
+}
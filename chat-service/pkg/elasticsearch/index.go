@@ -0,0 +1,316 @@
+package elasticsearch
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "strings"
+
+    "github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+const (
+    // MessagesAlias is the name every reader and writer uses. It points at
+    // exactly one versioned index, which is also its write index.
+    MessagesAlias = "messages"
+    // MessagesIndex is the versioned index created on a fresh cluster.
+    MessagesIndex = "messages_v1"
+)
+
+// messagesIndexDefinition returns the settings and mappings of a messages
+// index. Unknown fields are rejected so a typo in a document cannot silently
+// add a dynamically mapped field.
+func messagesIndexDefinition() map[string]interface{} {
+    return map[string]interface{}{
+        "settings": map[string]interface{}{
+            "analysis": map[string]interface{}{
+                "analyzer": map[string]interface{}{
+                    "message_body": map[string]interface{}{
+                        "type":        "custom",
+                        "char_filter": []string{"html_strip"},
+                        "tokenizer":   "standard",
+                        "filter":      []string{"lowercase", "asciifolding"},
+                    },
+                },
+            },
+        },
+        "mappings": map[string]interface{}{
+            "dynamic": "strict",
+            "properties": map[string]interface{}{
+                "id":         map[string]interface{}{"type": "long"},
+                "chat_id":    map[string]interface{}{"type": "long"},
+                "number":     map[string]interface{}{"type": "long"},
+                "body":       map[string]interface{}{"type": "text", "analyzer": "message_body"},
+                "created_at": map[string]interface{}{"type": "date"},
+                // Denormalized from the chat for application-wide search.
+                "application_token": map[string]interface{}{"type": "keyword"},
+                "chat_number":       map[string]interface{}{"type": "long"},
+            },
+        },
+    }
+}
+
+// EnsureMessagesIndex makes sure MessagesAlias resolves to a mapped index.
+// On a fresh cluster it creates MessagesIndex and points the alias at it;
+// when the alias already exists, fields added to the mapping since its index
+// was created are added to it.
+func (c *Client) EnsureMessagesIndex() error {
+    aliasExists, err := c.exists(c.es.Indices.ExistsAlias([]string{MessagesAlias}))
+    if err != nil {
+        return fmt.Errorf("failed to check alias %s: %w", MessagesAlias, err)
+    }
+    if aliasExists {
+        return c.putMessagesMapping(MessagesAlias)
+    }
+
+    // Before explicit mappings existed, the first indexed document created
+    // a dynamically mapped index named like the alias. An alias cannot take
+    // its name, so keep serving from it until it is migrated with reindex.
+    legacyExists, err := c.exists(c.es.Indices.Exists([]string{MessagesAlias}))
+    if err != nil {
+        return fmt.Errorf("failed to check index %s: %w", MessagesAlias, err)
+    }
+    if legacyExists {
+        fmt.Printf("Index %s is dynamically mapped, run reindex to migrate it to %s\n", MessagesAlias, MessagesIndex)
+        return nil
+    }
+
+    indexExists, err := c.exists(c.es.Indices.Exists([]string{MessagesIndex}))
+    if err != nil {
+        return fmt.Errorf("failed to check index %s: %w", MessagesIndex, err)
+    }
+    if !indexExists {
+        if err := c.CreateMessagesIndex(MessagesIndex); err != nil {
+            return err
+        }
+    }
+
+    return c.SwapAlias(MessagesAlias, MessagesIndex)
+}
+
+// CreateMessagesIndex creates an index with the messages settings and
+// mappings. It does not touch any alias.
+func (c *Client) CreateMessagesIndex(name string) error {
+    var buf bytes.Buffer
+    if err := json.NewEncoder(&buf).Encode(messagesIndexDefinition()); err != nil {
+        return fmt.Errorf("failed to encode index definition: %w", err)
+    }
+
+    res, err := c.es.Indices.Create(name, c.es.Indices.Create.WithBody(&buf))
+    if err != nil {
+        return fmt.Errorf("failed to create index %s: %w: %w", name, ErrUnavailable, err)
+    }
+    defer res.Body.Close()
+
+    if res.IsError() {
+        return responseError(res, fmt.Sprintf("failed to create index %s", name))
+    }
+
+    fmt.Printf("Created Elasticsearch index %s\n", name)
+    return nil
+}
+
+// putMessagesMapping adds any missing field of the messages mapping to the
+// indices behind name. Existing fields cannot change type this way; that
+// takes a reindex.
+func (c *Client) putMessagesMapping(name string) error {
+    mappings := messagesIndexDefinition()["mappings"].(map[string]interface{})
+
+    var buf bytes.Buffer
+    if err := json.NewEncoder(&buf).Encode(map[string]interface{}{"properties": mappings["properties"]}); err != nil {
+        return fmt.Errorf("failed to encode mapping: %w", err)
+    }
+
+    res, err := c.es.Indices.PutMapping([]string{name}, &buf)
+    if err != nil {
+        return fmt.Errorf("failed to update mapping of %s: %w: %w", name, ErrUnavailable, err)
+    }
+    defer res.Body.Close()
+
+    if res.IsError() {
+        return responseError(res, fmt.Sprintf("failed to update mapping of %s", name))
+    }
+    return nil
+}
+
+// SwapAlias points alias at index, as its write index, and removes it from
+// every other index in one atomic request.
+func (c *Client) SwapAlias(alias string, index string) error {
+    actions := []map[string]interface{}{
+        {"remove": map[string]interface{}{"index": "*", "alias": alias, "must_exist": false}},
+        {"add": map[string]interface{}{"index": index, "alias": alias, "is_write_index": true}},
+    }
+
+    var buf bytes.Buffer
+    if err := json.NewEncoder(&buf).Encode(map[string]interface{}{"actions": actions}); err != nil {
+        return fmt.Errorf("failed to encode alias actions: %w", err)
+    }
+
+    res, err := c.es.Indices.UpdateAliases(&buf)
+    if err != nil {
+        return fmt.Errorf("failed to point alias %s at %s: %w: %w", alias, index, ErrUnavailable, err)
+    }
+    defer res.Body.Close()
+
+    if res.IsError() {
+        return responseError(res, fmt.Sprintf("failed to point alias %s at %s", alias, index))
+    }
+
+    return nil
+}
+
+// ReplaceIndexWithAlias deletes the concrete index named alias and creates
+// alias pointing at index in the same atomic request. It migrates a legacy,
+// dynamically mapped index to an aliased one.
+func (c *Client) ReplaceIndexWithAlias(alias string, index string) error {
+    actions := []map[string]interface{}{
+        {"remove_index": map[string]interface{}{"index": alias}},
+        {"add": map[string]interface{}{"index": index, "alias": alias, "is_write_index": true}},
+    }
+
+    var buf bytes.Buffer
+    if err := json.NewEncoder(&buf).Encode(map[string]interface{}{"actions": actions}); err != nil {
+        return fmt.Errorf("failed to encode alias actions: %w", err)
+    }
+
+    res, err := c.es.Indices.UpdateAliases(&buf)
+    if err != nil {
+        return fmt.Errorf("failed to replace index %s with an alias: %w: %w", alias, ErrUnavailable, err)
+    }
+    defer res.Body.Close()
+
+    if res.IsError() {
+        return responseError(res, fmt.Sprintf("failed to replace index %s with an alias", alias))
+    }
+
+    return nil
+}
+
+// AliasTarget returns the write index of alias, or an empty string when the
+// alias does not exist.
+func (c *Client) AliasTarget(alias string) (string, error) {
+    res, err := c.es.Indices.GetAlias(c.es.Indices.GetAlias.WithName(alias))
+    if err != nil {
+        return "", fmt.Errorf("failed to get alias %s: %w: %w", alias, ErrUnavailable, err)
+    }
+    defer res.Body.Close()
+
+    if res.StatusCode == 404 {
+        return "", nil
+    }
+    if res.IsError() {
+        return "", responseError(res, fmt.Sprintf("failed to get alias %s", alias))
+    }
+
+    var indices map[string]struct {
+        Aliases map[string]struct {
+            IsWriteIndex *bool `json:"is_write_index"`
+        } `json:"aliases"`
+    }
+    if err := json.NewDecoder(res.Body).Decode(&indices); err != nil {
+        return "", fmt.Errorf("failed to decode alias response: %w", err)
+    }
+
+    target := ""
+    for name, index := range indices {
+        options := index.Aliases[alias]
+        if options.IsWriteIndex != nil && *options.IsWriteIndex {
+            return name, nil
+        }
+        target = name
+    }
+    if len(indices) > 1 {
+        return "", fmt.Errorf("alias %s points at %d indices without a write index", alias, len(indices))
+    }
+    return target, nil
+}
+
+// IndexExists reports whether name is an existing index or alias.
+func (c *Client) IndexExists(name string) (bool, error) {
+    exists, err := c.exists(c.es.Indices.Exists([]string{name}))
+    if err != nil {
+        return false, fmt.Errorf("failed to check index %s: %w", name, err)
+    }
+    return exists, nil
+}
+
+// DeleteIndex deletes an index. Deleting an index that does not exist is not
+// an error.
+func (c *Client) DeleteIndex(name string) error {
+    res, err := c.es.Indices.Delete([]string{name})
+    if err != nil {
+        return fmt.Errorf("failed to delete index %s: %w: %w", name, ErrUnavailable, err)
+    }
+    defer res.Body.Close()
+
+    if res.IsError() && res.StatusCode != 404 {
+        return responseError(res, fmt.Sprintf("failed to delete index %s", name))
+    }
+    return nil
+}
+
+// SetRefreshInterval changes how often an index makes new documents
+// searchable; "-1" disables refreshes during bulk loads.
+func (c *Client) SetRefreshInterval(index string, interval string) error {
+    var buf bytes.Buffer
+    settings := map[string]interface{}{
+        "index": map[string]interface{}{"refresh_interval": interval},
+    }
+    if err := json.NewEncoder(&buf).Encode(settings); err != nil {
+        return fmt.Errorf("failed to encode settings: %w", err)
+    }
+
+    res, err := c.es.Indices.PutSettings(&buf, c.es.Indices.PutSettings.WithIndex(index))
+    if err != nil {
+        return fmt.Errorf("failed to update settings of %s: %w: %w", index, ErrUnavailable, err)
+    }
+    defer res.Body.Close()
+
+    if res.IsError() {
+        return responseError(res, fmt.Sprintf("failed to update settings of %s", index))
+    }
+    return nil
+}
+
+// Refresh makes every document indexed so far searchable.
+func (c *Client) Refresh(index string) error {
+    res, err := c.es.Indices.Refresh(c.es.Indices.Refresh.WithIndex(index))
+    if err != nil {
+        return fmt.Errorf("failed to refresh %s: %w: %w", index, ErrUnavailable, err)
+    }
+    defer res.Body.Close()
+
+    if res.IsError() {
+        return responseError(res, fmt.Sprintf("failed to refresh %s", index))
+    }
+    return nil
+}
+
+func (c *Client) exists(res *esapi.Response, err error) (bool, error) {
+    if err != nil {
+        return false, fmt.Errorf("%w: %w", ErrUnavailable, err)
+    }
+    defer res.Body.Close()
+
+    switch {
+    case res.StatusCode == 200:
+        return true, nil
+    case res.StatusCode == 404:
+        return false, nil
+    default:
+        return false, responseError(res, "unexpected response")
+    }
+}
+
+// responseError turns an error response into an error, tagging it with
+// ErrUnavailable when the cluster could not serve the request.
+func responseError(res *esapi.Response, action string) error {
+    var errorMap map[string]interface{}
+    if err := json.NewDecoder(res.Body).Decode(&errorMap); err != nil {
+        errorMap = map[string]interface{}{"status": strings.TrimSpace(res.Status())}
+    }
+    if isUnavailableStatus(res.StatusCode) {
+        return fmt.Errorf("%s: %w: %v", action, ErrUnavailable, errorMap)
+    }
+    return fmt.Errorf("%s: %v", action, errorMap)
+}
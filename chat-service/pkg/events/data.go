@@ -0,0 +1,23 @@
+package events
+
+import "time"
+
+// Chat is the data of chat_created events in schema version 1. It is kept
+// apart from the storage model so model changes cannot alter the schema.
+type Chat struct {
+    ID            uint64    `json:"id"`
+    ApplicationID string    `json:"application_id"`
+    Number        int       `json:"number"`
+    MessagesCount int       `json:"messages_count"`
+    CreatedAt     time.Time `json:"created_at"`
+}
+
+// Message is the data of message_created, message_updated and
+// message_deleted events in schema version 1.
+type Message struct {
+    ID        uint64    `json:"id"`
+    ChatID    uint64    `json:"chat_id"`
+    Number    int       `json:"number"`
+    Body      string    `json:"body"`
+    CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,135 @@
+// Package events defines the envelope every event published by the chat
+// service is wrapped in. The envelope follows the CloudEvents 1.0 JSON
+// format, so consumers can identify, version and deduplicate events without
+// knowing the payload.
+package events
+
+import (
+    "crypto/rand"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "strings"
+    "time"
+)
+
+const (
+    SpecVersion = "1.0"
+    Source      = "/chat-service"
+
+    // SchemaVersion is the version of the data payloads. It is bumped on
+    // incompatible payload changes, which also changes the routing keys.
+    SchemaVersion = 1
+
+    // ContentType is the AMQP content type of a structured CloudEvent.
+    ContentType     = "application/cloudevents+json"
+    DataContentType = "application/json"
+
+    // HeaderPrefix prefixes the envelope attributes copied into AMQP
+    // headers, as in the CloudEvents AMQP binding.
+    HeaderPrefix = "cloudEvents_"
+)
+
+// ErrUnavailable is wrapped by publish errors caused by the event bus
+// being unable to take an event right now, as opposed to a malformed one.
+var ErrUnavailable = errors.New("event bus unavailable")
+
+// ErrNotEnvelope is returned by Parse for a body that is not an envelope,
+// such as a raw payload published before envelopes were introduced.
+var ErrNotEnvelope = errors.New("body is not an event envelope")
+
+// Envelope is a CloudEvent. Time is when the change happened, and the
+// schemaversion and applicationtoken extension attributes carry the
+// payload version and the application the event belongs to.
+type Envelope struct {
+    SpecVersion      string          `json:"specversion"`
+    ID               string          `json:"id"`
+    Source           string          `json:"source"`
+    Type             string          `json:"type"`
+    DataContentType  string          `json:"datacontenttype"`
+    Time             time.Time       `json:"time"`
+    SchemaVersion    int             `json:"schemaversion"`
+    ApplicationToken string          `json:"applicationtoken"`
+    Data             json.RawMessage `json:"data"`
+}
+
+// New wraps data in an envelope with a fresh random id.
+func New(eventType string, applicationToken string, occurredAt time.Time, data interface{}) (*Envelope, error) {
+    payload, err := json.Marshal(data)
+    if err != nil {
+        return nil, fmt.Errorf("failed to marshal %s data: %w", eventType, err)
+    }
+
+    id, err := newID()
+    if err != nil {
+        return nil, err
+    }
+
+    return &Envelope{
+        SpecVersion:      SpecVersion,
+        ID:               id,
+        Source:           Source,
+        Type:             eventType,
+        DataContentType:  DataContentType,
+        Time:             occurredAt.UTC(),
+        SchemaVersion:    SchemaVersion,
+        ApplicationToken: applicationToken,
+        Data:             payload,
+    }, nil
+}
+
+// Parse decodes an envelope, returning ErrNotEnvelope when body is valid
+// JSON without the CloudEvents attributes.
+func Parse(body []byte) (*Envelope, error) {
+    // Probe first: a bare payload's own fields, such as a numeric id, would
+    // not fit the envelope.
+    var probe struct {
+        SpecVersion string `json:"specversion"`
+    }
+    if err := json.Unmarshal(body, &probe); err != nil {
+        return nil, fmt.Errorf("failed to unmarshal event envelope: %w", err)
+    }
+    if probe.SpecVersion == "" {
+        return nil, ErrNotEnvelope
+    }
+    if probe.SpecVersion != SpecVersion {
+        return nil, fmt.Errorf("unsupported CloudEvents specversion %q", probe.SpecVersion)
+    }
+
+    envelope := &Envelope{}
+    if err := json.Unmarshal(body, envelope); err != nil {
+        return nil, fmt.Errorf("failed to unmarshal event envelope: %w", err)
+    }
+    return envelope, nil
+}
+
+// RoutingKey is the AMQP routing key of the envelope, such as
+// "message.created.v1", so consumers can bind to one type and version.
+func (e *Envelope) RoutingKey() string {
+    return fmt.Sprintf("%s.v%d", strings.ReplaceAll(e.Type, "_", "."), e.SchemaVersion)
+}
+
+// Headers returns the envelope attributes, without data, as AMQP headers.
+func (e *Envelope) Headers() map[string]interface{} {
+    return map[string]interface{}{
+        HeaderPrefix + "specversion":      e.SpecVersion,
+        HeaderPrefix + "id":               e.ID,
+        HeaderPrefix + "source":           e.Source,
+        HeaderPrefix + "type":             e.Type,
+        HeaderPrefix + "datacontenttype":  e.DataContentType,
+        HeaderPrefix + "time":             e.Time.Format(time.RFC3339Nano),
+        HeaderPrefix + "schemaversion":    int32(e.SchemaVersion),
+        HeaderPrefix + "applicationtoken": e.ApplicationToken,
+    }
+}
+
+// newID returns a random version 4 UUID.
+func newID() (string, error) {
+    var b [16]byte
+    if _, err := rand.Read(b[:]); err != nil {
+        return "", fmt.Errorf("failed to generate event id: %w", err)
+    }
+    b[6] = b[6]&0x0f | 0x40
+    b[8] = b[8]&0x3f | 0x80
+    return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
@@ -0,0 +1,168 @@
+package events
+
+import (
+    "encoding/json"
+    "errors"
+    "reflect"
+    "regexp"
+    "testing"
+    "time"
+)
+
+// These tests pin the wire format consumers rely on. A failure here means
+// the schema changed: bump SchemaVersion instead of editing the
+// expectations.
+
+var occurredAt = time.Date(2024, 11, 19, 20, 0, 0, 0, time.UTC)
+
+func fixedEnvelope(t *testing.T) *Envelope {
+    t.Helper()
+
+    envelope, err := New("message_created", "app-token", occurredAt, Message{
+        ID:        12,
+        ChatID:    3,
+        Number:    7,
+        Body:      "hi",
+        CreatedAt: occurredAt,
+    })
+    if err != nil {
+        t.Fatalf("New: %v", err)
+    }
+    envelope.ID = "2f1d3c5e-6a7b-4c8d-9e0f-1a2b3c4d5e6f"
+    return envelope
+}
+
+func TestEnvelopeJSON(t *testing.T) {
+    got, err := json.Marshal(fixedEnvelope(t))
+    if err != nil {
+        t.Fatalf("Marshal: %v", err)
+    }
+
+    want := `{"specversion":"1.0",` +
+        `"id":"2f1d3c5e-6a7b-4c8d-9e0f-1a2b3c4d5e6f",` +
+        `"source":"/chat-service",` +
+        `"type":"message_created",` +
+        `"datacontenttype":"application/json",` +
+        `"time":"2024-11-19T20:00:00Z",` +
+        `"schemaversion":1,` +
+        `"applicationtoken":"app-token",` +
+        `"data":{"id":12,"chat_id":3,"number":7,"body":"hi","created_at":"2024-11-19T20:00:00Z"}}`
+    if string(got) != want {
+        t.Errorf("envelope JSON\n got: %s\nwant: %s", got, want)
+    }
+}
+
+func TestChatDataJSON(t *testing.T) {
+    got, err := json.Marshal(Chat{
+        ID:            5,
+        ApplicationID: "app-token",
+        Number:        2,
+        MessagesCount: 0,
+        CreatedAt:     occurredAt,
+    })
+    if err != nil {
+        t.Fatalf("Marshal: %v", err)
+    }
+
+    want := `{"id":5,"application_id":"app-token","number":2,"messages_count":0,"created_at":"2024-11-19T20:00:00Z"}`
+    if string(got) != want {
+        t.Errorf("chat JSON\n got: %s\nwant: %s", got, want)
+    }
+}
+
+func TestEnvelopeHeaders(t *testing.T) {
+    got := fixedEnvelope(t).Headers()
+
+    want := map[string]interface{}{
+        "cloudEvents_specversion":      "1.0",
+        "cloudEvents_id":               "2f1d3c5e-6a7b-4c8d-9e0f-1a2b3c4d5e6f",
+        "cloudEvents_source":           "/chat-service",
+        "cloudEvents_type":             "message_created",
+        "cloudEvents_datacontenttype":  "application/json",
+        "cloudEvents_time":             "2024-11-19T20:00:00Z",
+        "cloudEvents_schemaversion":    int32(1),
+        "cloudEvents_applicationtoken": "app-token",
+    }
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("headers\n got: %#v\nwant: %#v", got, want)
+    }
+}
+
+func TestRoutingKey(t *testing.T) {
+    tests := map[string]string{
+        "chat_created":    "chat.created.v1",
+        "message_created": "message.created.v1",
+        "message_updated": "message.updated.v1",
+        "message_deleted": "message.deleted.v1",
+    }
+    for eventType, want := range tests {
+        envelope := &Envelope{Type: eventType, SchemaVersion: SchemaVersion}
+        if got := envelope.RoutingKey(); got != want {
+            t.Errorf("RoutingKey(%s) = %q, want %q", eventType, got, want)
+        }
+    }
+}
+
+func TestNewAssignsUniqueIDs(t *testing.T) {
+    uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+    first, err := New("chat_created", "app-token", occurredAt, Chat{})
+    if err != nil {
+        t.Fatalf("New: %v", err)
+    }
+    second, err := New("chat_created", "app-token", occurredAt, Chat{})
+    if err != nil {
+        t.Fatalf("New: %v", err)
+    }
+
+    if !uuid.MatchString(first.ID) {
+        t.Errorf("id %q is not a version 4 UUID", first.ID)
+    }
+    if first.ID == second.ID {
+        t.Errorf("two envelopes got the same id %q", first.ID)
+    }
+}
+
+func TestNewUsesUTC(t *testing.T) {
+    local := time.Date(2024, 11, 19, 22, 0, 0, 0, time.FixedZone("EET", 2*60*60))
+
+    envelope, err := New("chat_created", "app-token", local, Chat{})
+    if err != nil {
+        t.Fatalf("New: %v", err)
+    }
+    if !envelope.Time.Equal(occurredAt) || envelope.Time.Location() != time.UTC {
+        t.Errorf("Time = %v, want %v", envelope.Time, occurredAt)
+    }
+}
+
+func TestParseRoundTrip(t *testing.T) {
+    want := fixedEnvelope(t)
+    body, err := json.Marshal(want)
+    if err != nil {
+        t.Fatalf("Marshal: %v", err)
+    }
+
+    got, err := Parse(body)
+    if err != nil {
+        t.Fatalf("Parse: %v", err)
+    }
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("Parse\n got: %+v\nwant: %+v", got, want)
+    }
+}
+
+func TestParseRejectsNonEnvelopes(t *testing.T) {
+    _, err := Parse([]byte(`{"id":12,"chat_id":3,"number":7}`))
+    if !errors.Is(err, ErrNotEnvelope) {
+        t.Errorf("bare payload: err = %v, want ErrNotEnvelope", err)
+    }
+
+    _, err = Parse([]byte(`{"specversion":"0.3","id":"x","type":"chat_created"}`))
+    if err == nil || errors.Is(err, ErrNotEnvelope) {
+        t.Errorf("specversion 0.3: err = %v, want an unsupported version error", err)
+    }
+
+    if _, err := Parse([]byte(`not json`)); err == nil {
+        t.Error("invalid JSON: err = nil")
+    }
+}
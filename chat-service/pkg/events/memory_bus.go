@@ -0,0 +1,99 @@
+package events
+
+import (
+    "context"
+    "fmt"
+    "sync"
+)
+
+// MemoryBus is an in-process event bus for local development and tests.
+// Every subscriber interested in an event's type gets its own copy through
+// a buffered channel. Events published with no interested subscriber are
+// dropped, as a broker drops messages no queue is bound for.
+type MemoryBus struct {
+    buffer int
+
+    mu          sync.RWMutex
+    subscribers map[*Subscription]struct{}
+}
+
+// Subscription receives the events of the types it was created for.
+type Subscription struct {
+    bus    *MemoryBus
+    types  map[string]bool
+    events chan *Envelope
+    once   sync.Once
+}
+
+func NewMemoryBus(buffer int) *MemoryBus {
+    if buffer <= 0 {
+        buffer = 1000
+    }
+    return &MemoryBus{
+        buffer:      buffer,
+        subscribers: make(map[*Subscription]struct{}),
+    }
+}
+
+// Publish hands envelope to every interested subscriber without waiting.
+// A subscriber whose buffer is full misses the event and Publish returns
+// an error wrapping ErrUnavailable, so the outbox retries it; subscribers
+// that did receive it then see it twice and deduplicate on its id.
+func (b *MemoryBus) Publish(ctx context.Context, envelope *Envelope) error {
+    b.mu.RLock()
+    defer b.mu.RUnlock()
+
+    missed := 0
+    for subscription := range b.subscribers {
+        if !subscription.wants(envelope.Type) {
+            continue
+        }
+        select {
+        case subscription.events <- envelope:
+        default:
+            missed++
+        }
+    }
+
+    if missed > 0 {
+        return fmt.Errorf("%w: %d subscribers too slow for %s event", ErrUnavailable, missed, envelope.Type)
+    }
+    return nil
+}
+
+// Subscribe returns a subscription to the given event types, or to every
+// event when none are given.
+func (b *MemoryBus) Subscribe(eventTypes ...string) *Subscription {
+    subscription := &Subscription{
+        bus:    b,
+        types:  make(map[string]bool, len(eventTypes)),
+        events: make(chan *Envelope, b.buffer),
+    }
+    for _, eventType := range eventTypes {
+        subscription.types[eventType] = true
+    }
+
+    b.mu.Lock()
+    b.subscribers[subscription] = struct{}{}
+    b.mu.Unlock()
+
+    return subscription
+}
+
+func (s *Subscription) Events() <-chan *Envelope {
+    return s.events
+}
+
+// Close stops the subscription and closes its channel.
+func (s *Subscription) Close() {
+    s.once.Do(func() {
+        s.bus.mu.Lock()
+        delete(s.bus.subscribers, s)
+        s.bus.mu.Unlock()
+        close(s.events)
+    })
+}
+
+func (s *Subscription) wants(eventType string) bool {
+    return len(s.types) == 0 || s.types[eventType]
+}
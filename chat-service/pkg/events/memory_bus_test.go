@@ -0,0 +1,59 @@
+package events
+
+import (
+    "context"
+    "errors"
+    "testing"
+)
+
+func TestMemoryBusDeliversByType(t *testing.T) {
+    bus := NewMemoryBus(10)
+    all := bus.Subscribe()
+    chats := bus.Subscribe("chat_created")
+    defer all.Close()
+    defer chats.Close()
+
+    for _, eventType := range []string{"chat_created", "message_created"} {
+        if err := bus.Publish(context.Background(), &Envelope{ID: eventType, Type: eventType}); err != nil {
+            t.Fatalf("Publish(%s): %v", eventType, err)
+        }
+    }
+
+    if got := len(all.Events()); got != 2 {
+        t.Errorf("subscriber to all events got %d events, want 2", got)
+    }
+    if got := len(chats.Events()); got != 1 {
+        t.Fatalf("chat_created subscriber got %d events, want 1", got)
+    }
+    if envelope := <-chats.Events(); envelope.Type != "chat_created" {
+        t.Errorf("chat_created subscriber got a %s event", envelope.Type)
+    }
+}
+
+func TestMemoryBusReportsSlowSubscribers(t *testing.T) {
+    bus := NewMemoryBus(1)
+    subscription := bus.Subscribe()
+    defer subscription.Close()
+
+    envelope := &Envelope{ID: "1", Type: "chat_created"}
+    if err := bus.Publish(context.Background(), envelope); err != nil {
+        t.Fatalf("first Publish: %v", err)
+    }
+    if err := bus.Publish(context.Background(), envelope); !errors.Is(err, ErrUnavailable) {
+        t.Errorf("Publish to a full subscriber: err = %v, want ErrUnavailable", err)
+    }
+}
+
+func TestMemoryBusClose(t *testing.T) {
+    bus := NewMemoryBus(10)
+    subscription := bus.Subscribe()
+    subscription.Close()
+    subscription.Close()
+
+    if _, ok := <-subscription.Events(); ok {
+        t.Error("Events() still open after Close")
+    }
+    if err := bus.Publish(context.Background(), &Envelope{Type: "chat_created"}); err != nil {
+        t.Errorf("Publish after Close: %v", err)
+    }
+}
@@ -3,99 +3,538 @@ package rabbitmq
 import (
     "context"
     "encoding/json"
+    "errors"
     "fmt"
+    "sync"
     "time"
     "github.com/streadway/amqp"
+
+    "chat-service/pkg/events"
 )
 
+var (
+    // ErrUnavailable is wrapped by every error caused by the broker being
+    // unreachable or not confirming a publish. It wraps
+    // events.ErrUnavailable.
+    ErrUnavailable = fmt.Errorf("rabbitmq unavailable: %w", events.ErrUnavailable)
+
+    // ErrBufferFull is returned by publishes attempted while disconnected
+    // once BufferSize publishes are already waiting for the connection.
+    ErrBufferFull = fmt.Errorf("%w: publish buffer full", ErrUnavailable)
+
+    // ErrConfirmTimeout is returned when a publish is not confirmed within
+    // PublishTimeout, including time spent waiting in the buffer.
+    ErrConfirmTimeout = fmt.Errorf("%w: publish not confirmed in time", ErrUnavailable)
+
+    // ErrNacked is returned when the broker refused to take responsibility
+    // for a message.
+    ErrNacked = fmt.Errorf("%w: publish nacked by broker", ErrUnavailable)
+
+    ErrClosed = errors.New("rabbitmq client closed")
+)
+
+var exchanges = []string{"chat_created", "message_created", "message_updated", "message_deleted"}
+
 type Config struct {
     Host     string
     Port     string
     User     string
     Password string
+
+    // ReconnectMinBackoff and ReconnectMaxBackoff bound the delay between
+    // reconnect attempts, which doubles after every failure.
+    ReconnectMinBackoff time.Duration
+    ReconnectMaxBackoff time.Duration
+    // PublishTimeout bounds how long a publish waits for its confirm.
+    PublishTimeout time.Duration
+    // BufferSize bounds how many publishes may wait for a reconnect.
+    BufferSize int
+
+    Topology Topology
 }
 
+// Client publishes to and consumes from the chat event exchanges. When the
+// connection or one of its channels closes, it reconnects with backoff,
+// redeclares the exchanges and queues and resumes the consumers. Publishes use
+// publisher confirms; while disconnected they wait in a bounded buffer and
+// are sent in order once the connection is back.
 type Client struct {
-    connection *amqp.Connection
-    channel    *amqp.Channel
+    cfg Config
+    dsn string
+
+    mu      sync.Mutex
+    session *session
+    buffer  chan *outgoing
+
+    // consumerMu is held while consumers are started. It is separate from
+    // mu because starting one waits for the broker, which may in turn wait
+    // for confirms to be dispatched under mu.
+    consumerMu sync.Mutex
+    consumers  []*consumer
+
+    closed    chan struct{}
+    closeOnce sync.Once
+    wg        sync.WaitGroup
+}
+
+// session is one connection with its publishing and consuming channels.
+// Delivery tags of confirms restart at 1 on every new channel, so pending
+// confirms are tracked per session.
+type session struct {
+    conn      *amqp.Connection
+    publishCh *amqp.Channel
+    consumeCh *amqp.Channel
+    confirms  chan amqp.Confirmation
+    nextTag   uint64
+    pending   map[uint64]chan error
+}
+
+type outgoing struct {
+    ctx        context.Context
+    exchange   string
+    routingKey string
+    properties amqp.Publishing
+    result     chan error
+}
+
+type consumer struct {
+    queue      string
+    prefetch   int
+    deliveries chan amqp.Delivery
 }
 
 func NewClient(cfg Config) (*Client, error) {
-    dsn := fmt.Sprintf("amqp://%s:%s@%s:%s/", 
-        cfg.User, cfg.Password, cfg.Host, cfg.Port)
+    if cfg.ReconnectMinBackoff <= 0 {
+        cfg.ReconnectMinBackoff = time.Second
+    }
+    if cfg.ReconnectMaxBackoff < cfg.ReconnectMinBackoff {
+        cfg.ReconnectMaxBackoff = 30 * time.Second
+    }
+    if cfg.PublishTimeout <= 0 {
+        cfg.PublishTimeout = 5 * time.Second
+    }
+    if cfg.BufferSize <= 0 {
+        cfg.BufferSize = 1000
+    }
+    if cfg.Topology.RetryExchange == "" {
+        cfg.Topology.RetryExchange = "chat.retry"
+    }
+    if cfg.Topology.DeadLetterExchange == "" {
+        cfg.Topology.DeadLetterExchange = "chat.dlx"
+    }
+    if cfg.Topology.RetryDelay <= 0 {
+        cfg.Topology.RetryDelay = 30 * time.Second
+    }
+    if cfg.Topology.MaxRetries <= 0 {
+        cfg.Topology.MaxRetries = 5
+    }
+
+    c := &Client{
+        cfg: cfg,
+        dsn: fmt.Sprintf("amqp://%s:%s@%s:%s/",
+            cfg.User, cfg.Password, cfg.Host, cfg.Port),
+        buffer: make(chan *outgoing, cfg.BufferSize),
+        closed: make(chan struct{}),
+    }
+
+    s, err := c.connect()
+    if err != nil {
+        return nil, err
+    }
+    c.session = s
+
+    c.wg.Add(1)
+    go c.supervise(s)
 
-    conn, err := amqp.Dial(dsn)
+    return c, nil
+}
+
+// connect dials the broker, opens both channels, puts the publishing one in
+// confirm mode and declares the exchanges and the queue topology.
+func (c *Client) connect() (*session, error) {
+    conn, err := amqp.Dial(c.dsn)
     if err != nil {
         return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
     }
 
-    ch, err := conn.Channel()
+    publishCh, err := conn.Channel()
     if err != nil {
         conn.Close()
         return nil, fmt.Errorf("failed to create RabbitMQ channel: %w", err)
     }
+    if err := publishCh.Confirm(false); err != nil {
+        conn.Close()
+        return nil, fmt.Errorf("failed to enable publisher confirms: %w", err)
+    }
 
-    queues := []string{"chat_created", "message_created"}
-    for _, queue := range queues {
-        if err := ch.ExchangeDeclare(
-            queue,   // name
-            "topic", // type
-            true,    // durable
-            false,   // auto-deleted
-            false,   // internal
-            false,   // no-wait
-            nil,     // arguments
+    for _, exchange := range exchanges {
+        if err := publishCh.ExchangeDeclare(
+            exchange, // name
+            "topic",  // type
+            true,     // durable
+            false,    // auto-deleted
+            false,    // internal
+            false,    // no-wait
+            nil,      // arguments
         ); err != nil {
             conn.Close()
-            return nil, fmt.Errorf("failed to declare exchange %s: %w", queue, err)
+            return nil, fmt.Errorf("failed to declare exchange %s: %w", exchange, err)
         }
     }
 
-    return &Client{
-        connection: conn,
-        channel:    ch,
-    }, nil
+    if err := declareTopology(publishCh, c.cfg.Topology); err != nil {
+        conn.Close()
+        return nil, err
+    }
+
+    consumeCh, err := conn.Channel()
+    if err != nil {
+        conn.Close()
+        return nil, fmt.Errorf("failed to create RabbitMQ channel: %w", err)
+    }
+
+    s := &session{
+        conn:      conn,
+        publishCh: publishCh,
+        consumeCh: consumeCh,
+        confirms:  publishCh.NotifyPublish(make(chan amqp.Confirmation, c.cfg.BufferSize)),
+        pending:   make(map[uint64]chan error),
+    }
+
+    c.wg.Add(1)
+    go c.dispatchConfirms(s)
+
+    return s, nil
 }
 
-func (c *Client) Close() error {
-    if err := c.channel.Close(); err != nil {
-        return fmt.Errorf("failed to close channel: %w", err)
+// supervise waits for the current session to break and replaces it, until
+// the client is closed.
+func (c *Client) supervise(s *session) {
+    defer c.wg.Done()
+
+    for {
+        connClosed := s.conn.NotifyClose(make(chan *amqp.Error, 1))
+        publishClosed := s.publishCh.NotifyClose(make(chan *amqp.Error, 1))
+        consumeClosed := s.consumeCh.NotifyClose(make(chan *amqp.Error, 1))
+
+        var reason *amqp.Error
+        select {
+        case <-c.closed:
+            return
+        case reason = <-connClosed:
+        case reason = <-publishClosed:
+        case reason = <-consumeClosed:
+        }
+
+        c.mu.Lock()
+        if c.session == s {
+            c.session = nil
+        }
+        c.mu.Unlock()
+        s.conn.Close()
+
+        select {
+        case <-c.closed:
+            return
+        default:
+        }
+        fmt.Printf("RabbitMQ connection lost, reconnecting: %v\n", reason)
+
+        next, ok := c.reconnect()
+        if !ok {
+            return
+        }
+        s = next
+        fmt.Println("RabbitMQ connection restored")
+    }
+}
+
+// reconnect retries connect with exponential backoff until it succeeds and
+// the session is resumed, or the client is closed.
+func (c *Client) reconnect() (*session, bool) {
+    backoff := c.cfg.ReconnectMinBackoff
+    for {
+        select {
+        case <-c.closed:
+            return nil, false
+        case <-time.After(backoff):
+        }
+
+        s, err := c.connect()
+        if err == nil {
+            err = c.resume(s)
+            if err == nil {
+                return s, true
+            }
+            s.conn.Close()
+        }
+
+        backoff *= 2
+        if backoff > c.cfg.ReconnectMaxBackoff {
+            backoff = c.cfg.ReconnectMaxBackoff
+        }
+        fmt.Printf("RabbitMQ reconnect failed, retrying in %s: %v\n", backoff, err)
     }
-    if err := c.connection.Close(); err != nil {
-        return fmt.Errorf("failed to close connection: %w", err)
+}
+
+// resume restarts the consumers on a new session, sends the buffered
+// publishes and makes the session current. Holding mu while draining keeps
+// new publishes behind the buffered ones.
+func (c *Client) resume(s *session) error {
+    c.consumerMu.Lock()
+    defer c.consumerMu.Unlock()
+
+    select {
+    case <-c.closed:
+        return ErrClosed
+    default:
+    }
+
+    for _, consumer := range c.consumers {
+        if err := c.startConsumer(s, consumer); err != nil {
+            return err
+        }
+    }
+
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    for len(c.buffer) > 0 {
+        c.send(s, <-c.buffer)
     }
+    c.session = s
     return nil
 }
 
-func (c *Client) PublishChatCreated(ctx context.Context, data interface{}) error {
-    body, err := json.Marshal(data)
+// dispatchConfirms hands every confirm of a session to the publish waiting
+// for it. Once the channel is gone, publishes still waiting will never be
+// confirmed and fail.
+func (c *Client) dispatchConfirms(s *session) {
+    defer c.wg.Done()
+
+    for confirm := range s.confirms {
+        c.mu.Lock()
+        result, ok := s.pending[confirm.DeliveryTag]
+        delete(s.pending, confirm.DeliveryTag)
+        c.mu.Unlock()
+
+        if !ok {
+            continue
+        }
+        if confirm.Ack {
+            result <- nil
+        } else {
+            result <- ErrNacked
+        }
+    }
+
+    c.mu.Lock()
+    for tag, result := range s.pending {
+        result <- fmt.Errorf("%w: connection lost before confirm", ErrUnavailable)
+        delete(s.pending, tag)
+    }
+    c.mu.Unlock()
+}
+
+func (c *Client) Close() error {
+    err := ErrClosed
+    c.closeOnce.Do(func() {
+        close(c.closed)
+
+        c.mu.Lock()
+        s := c.session
+        c.session = nil
+        for len(c.buffer) > 0 {
+            (<-c.buffer).result <- ErrClosed
+        }
+        c.mu.Unlock()
+
+        err = nil
+        if s != nil {
+            if closeErr := s.conn.Close(); closeErr != nil {
+                err = fmt.Errorf("failed to close connection: %w", closeErr)
+            }
+        }
+
+        c.wg.Wait()
+
+        c.consumerMu.Lock()
+        for _, consumer := range c.consumers {
+            close(consumer.deliveries)
+        }
+        c.consumerMu.Unlock()
+    })
+    return err
+}
+
+// Publish sends envelope to the exchange named after its type, with its
+// type and version as routing key and its attributes as headers, and waits
+// for the broker to confirm it.
+func (c *Client) Publish(ctx context.Context, envelope *events.Envelope) error {
+    known := false
+    for _, exchange := range exchanges {
+        known = known || exchange == envelope.Type
+    }
+    if !known {
+        return fmt.Errorf("no exchange for event type %q", envelope.Type)
+    }
+
+    body, err := json.Marshal(envelope)
     if err != nil {
-        return fmt.Errorf("failed to marshal chat data: %w", err)
+        return fmt.Errorf("failed to marshal %s event: %w", envelope.Type, err)
+    }
+
+    return c.deliver(ctx, envelope.Type, envelope.RoutingKey(), amqp.Publishing{
+        Headers:     amqp.Table(envelope.Headers()),
+        ContentType: events.ContentType,
+        MessageId:   envelope.ID,
+        Type:        envelope.Type,
+        AppId:       envelope.Source,
+        Timestamp:   envelope.Time,
+        Body:        body,
+    })
+}
+
+// Consume starts a manually acknowledged consumer on a work queue of the
+// topology. prefetch bounds the number of unacknowledged deliveries the
+// broker will hand out at once. The returned channel survives reconnects
+// and is closed by Close; deliveries received before a reconnect can no
+// longer be acknowledged and are redelivered. Deliveries that fail should
+// be handed to Retry or DeadLetter rather than requeued.
+func (c *Client) Consume(queue string, prefetch int) (<-chan amqp.Delivery, error) {
+    if !c.cfg.Topology.hasQueue(queue) {
+        return nil, fmt.Errorf("failed to consume from %s: %w", queue, ErrUnknownQueue)
+    }
+
+    c.consumerMu.Lock()
+    defer c.consumerMu.Unlock()
+
+    select {
+    case <-c.closed:
+        return nil, ErrClosed
+    default:
     }
 
-    return c.publish("chat_created", body)
+    consumer := &consumer{
+        queue:      queue,
+        prefetch:   prefetch,
+        deliveries: make(chan amqp.Delivery),
+    }
+
+    c.mu.Lock()
+    s := c.session
+    c.mu.Unlock()
+
+    // Without a session the consumer starts with the next one.
+    if s != nil {
+        if err := c.startConsumer(s, consumer); err != nil {
+            return nil, err
+        }
+    }
+    c.consumers = append(c.consumers, consumer)
+
+    return consumer.deliveries, nil
 }
 
-func (c *Client) PublishMessageCreated(ctx context.Context, data interface{}) error {
-    body, err := json.Marshal(data)
+func (c *Client) startConsumer(s *session, consumer *consumer) error {
+    if err := s.consumeCh.Qos(consumer.prefetch, 0, false); err != nil {
+        return fmt.Errorf("failed to set prefetch for %s: %w", consumer.queue, err)
+    }
+
+    deliveries, err := s.consumeCh.Consume(
+        consumer.queue, // queue
+        "",             // consumer tag
+        false,          // auto-ack
+        false,          // exclusive
+        false,          // no-local
+        false,          // no-wait
+        nil,            // arguments
+    )
     if err != nil {
-        return fmt.Errorf("failed to marshal message data: %w", err)
+        return fmt.Errorf("failed to consume from %s: %w", consumer.queue, err)
     }
 
-    return c.publish("message_created", body)
+    c.wg.Add(1)
+    go func() {
+        defer c.wg.Done()
+        for d := range deliveries {
+            select {
+            case consumer.deliveries <- d:
+            case <-c.closed:
+                return
+            }
+        }
+    }()
+
+    return nil
 }
 
-func (c *Client) publish(queue string, body []byte) error {
-    return c.channel.Publish(
-        queue, // exchange
-        "",    // routing key
-        false, // mandatory
-        false, // immediate
-        amqp.Publishing{
-            ContentType:  "application/json",
-            Body:        body,
-            DeliveryMode: amqp.Persistent,
-            Timestamp:   time.Now(),
-        },
+// deliver sends a persistent message to exchange and waits for the broker
+// to confirm it. While disconnected the publish is buffered instead;
+// PublishTimeout covers the wait in the buffer as well.
+func (c *Client) deliver(ctx context.Context, exchange string, routingKey string, properties amqp.Publishing) error {
+    ctx, cancel := context.WithTimeout(ctx, c.cfg.PublishTimeout)
+    defer cancel()
+
+    properties.DeliveryMode = amqp.Persistent
+
+    msg := &outgoing{
+        ctx:        ctx,
+        exchange:   exchange,
+        routingKey: routingKey,
+        properties: properties,
+        result:     make(chan error, 1),
+    }
+
+    c.mu.Lock()
+    select {
+    case <-c.closed:
+        c.mu.Unlock()
+        return ErrClosed
+    default:
+    }
+    if c.session != nil {
+        c.send(c.session, msg)
+    } else {
+        select {
+        case c.buffer <- msg:
+        default:
+            c.mu.Unlock()
+            return ErrBufferFull
+        }
+    }
+    c.mu.Unlock()
+
+    select {
+    case err := <-msg.result:
+        return err
+    case <-ctx.Done():
+        return fmt.Errorf("%w: %w", ErrConfirmTimeout, ctx.Err())
+    }
+}
+
+// send publishes msg on s and registers it for the matching confirm.
+// Buffered publishes whose caller gave up are dropped. Callers hold c.mu.
+func (c *Client) send(s *session, msg *outgoing) {
+    if msg.ctx.Err() != nil {
+        return
+    }
+
+    s.nextTag++
+    tag := s.nextTag
+    s.pending[tag] = msg.result
+
+    err := s.publishCh.Publish(
+        msg.exchange,   // exchange
+        msg.routingKey, // routing key
+        false,          // mandatory
+        false,          // immediate
+        msg.properties,
     )
+    if err != nil {
+        // The channel only counts publishes that were sent.
+        delete(s.pending, tag)
+        s.nextTag--
+        msg.result <- fmt.Errorf("%w: %w", ErrUnavailable, err)
+    }
 }
@@ -0,0 +1,180 @@
+package rabbitmq
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    "github.com/streadway/amqp"
+)
+
+// Headers added to a delivery when it is moved to a dead-letter queue.
+const (
+    headerError            = "x-error"
+    headerOriginalExchange = "x-original-exchange"
+    headerDeadLetteredAt   = "x-dead-lettered-at"
+)
+
+// DeadLetter is a message parked in the dead-letter queue of a work queue.
+type DeadLetter struct {
+    Exchange       string
+    Body           []byte
+    Error          string
+    Retries        int64
+    DeadLetteredAt time.Time
+}
+
+// Retry settles a delivery from queue that failed with reason. It goes to
+// the retry queue and comes back after the retry delay, or to the
+// dead-letter queue once it was already retried MaxRetries times.
+func (c *Client) Retry(ctx context.Context, d amqp.Delivery, queue string, reason error) error {
+    if retries(d, queue) >= int64(c.cfg.Topology.MaxRetries) {
+        return c.DeadLetter(ctx, d, queue, reason)
+    }
+    if err := d.Nack(false, false); err != nil {
+        return fmt.Errorf("failed to reject delivery: %w", err)
+    }
+    return nil
+}
+
+// DeadLetter moves a delivery from queue that can never be processed
+// straight to the dead-letter queue, recording reason. If the copy cannot
+// be published, the delivery is requeued instead of lost.
+func (c *Client) DeadLetter(ctx context.Context, d amqp.Delivery, queue string, reason error) error {
+    headers := amqp.Table{}
+    for key, value := range d.Headers {
+        headers[key] = value
+    }
+    headers[headerError] = reason.Error()
+    headers[headerOriginalExchange] = d.Exchange
+    headers[headerDeadLetteredAt] = time.Now().UTC()
+
+    if err := c.deliver(ctx, c.cfg.Topology.DeadLetterExchange, queue, republished(d, headers)); err != nil {
+        if nackErr := d.Nack(false, true); nackErr != nil {
+            return fmt.Errorf("failed to requeue delivery after %v: %w", err, nackErr)
+        }
+        return fmt.Errorf("failed to dead-letter delivery: %w", err)
+    }
+    if err := d.Ack(false); err != nil {
+        return fmt.Errorf("failed to ack dead-lettered delivery: %w", err)
+    }
+    return nil
+}
+
+// PeekDeadLetters returns up to limit messages from the head of the
+// dead-letter queue of queue without removing them.
+func (c *Client) PeekDeadLetters(queue string, limit int) ([]DeadLetter, error) {
+    ch, err := c.adminChannel(queue)
+    if err != nil {
+        return nil, err
+    }
+    // Closing the channel puts every unacknowledged message back in place.
+    defer ch.Close()
+
+    var letters []DeadLetter
+    for len(letters) < limit {
+        d, ok, err := ch.Get(DeadLetterQueue(queue), false)
+        if err != nil {
+            return nil, fmt.Errorf("%w: failed to read dead letters of %s: %w", ErrUnavailable, queue, err)
+        }
+        if !ok {
+            break
+        }
+        letters = append(letters, deadLetterFromDelivery(d, queue))
+    }
+    return letters, nil
+}
+
+// ReplayDeadLetters moves up to limit messages from the dead-letter queue
+// of queue back to queue itself, with a fresh retry count. They are sent
+// straight to the queue rather than to their original exchange, so other
+// queues bound to that exchange do not see them twice. It returns how many
+// were moved; messages are removed only once their copy is confirmed.
+func (c *Client) ReplayDeadLetters(ctx context.Context, queue string, limit int) (int, error) {
+    ch, err := c.adminChannel(queue)
+    if err != nil {
+        return 0, err
+    }
+    defer ch.Close()
+
+    replayed := 0
+    for replayed < limit {
+        d, ok, err := ch.Get(DeadLetterQueue(queue), false)
+        if err != nil {
+            return replayed, fmt.Errorf("%w: failed to read dead letters of %s: %w", ErrUnavailable, queue, err)
+        }
+        if !ok {
+            break
+        }
+
+        headers := amqp.Table{}
+        for key, value := range d.Headers {
+            switch key {
+            case "x-death", headerError, headerOriginalExchange, headerDeadLetteredAt:
+            default:
+                headers[key] = value
+            }
+        }
+
+        if err := c.deliver(ctx, "", queue, republished(d, headers)); err != nil {
+            return replayed, fmt.Errorf("failed to replay dead letter: %w", err)
+        }
+        if err := d.Ack(false); err != nil {
+            return replayed, fmt.Errorf("%w: failed to remove replayed dead letter: %w", ErrUnavailable, err)
+        }
+        replayed++
+    }
+    return replayed, nil
+}
+
+// adminChannel opens a short-lived channel for reading a dead-letter queue.
+func (c *Client) adminChannel(queue string) (*amqp.Channel, error) {
+    if !c.cfg.Topology.hasQueue(queue) {
+        return nil, fmt.Errorf("failed to open dead letters of %s: %w", queue, ErrUnknownQueue)
+    }
+
+    c.mu.Lock()
+    s := c.session
+    c.mu.Unlock()
+    if s == nil {
+        return nil, fmt.Errorf("%w: not connected", ErrUnavailable)
+    }
+
+    ch, err := s.conn.Channel()
+    if err != nil {
+        return nil, fmt.Errorf("%w: failed to create RabbitMQ channel: %w", ErrUnavailable, err)
+    }
+    return ch, nil
+}
+
+func deadLetterFromDelivery(d amqp.Delivery, queue string) DeadLetter {
+    letter := DeadLetter{
+        Exchange: d.Exchange,
+        Body:     d.Body,
+        Retries:  retries(d, queue),
+    }
+    if exchange, ok := d.Headers[headerOriginalExchange].(string); ok {
+        letter.Exchange = exchange
+    }
+    if reason, ok := d.Headers[headerError].(string); ok {
+        letter.Error = reason
+    }
+    if at, ok := d.Headers[headerDeadLetteredAt].(time.Time); ok {
+        letter.DeadLetteredAt = at
+    }
+    return letter
+}
+
+// republished is d as a new message with the given headers, keeping its
+// other properties.
+func republished(d amqp.Delivery, headers amqp.Table) amqp.Publishing {
+    return amqp.Publishing{
+        Headers:     headers,
+        ContentType: d.ContentType,
+        MessageId:   d.MessageId,
+        Type:        d.Type,
+        AppId:       d.AppId,
+        Timestamp:   d.Timestamp,
+        Body:        d.Body,
+    }
+}
@@ -0,0 +1,144 @@
+package rabbitmq
+
+import (
+    "errors"
+    "fmt"
+    "strings"
+    "time"
+
+    "github.com/streadway/amqp"
+)
+
+// ErrUnknownQueue is returned for a work queue that is not part of the
+// configured topology.
+var ErrUnknownQueue = errors.New("queue is not part of the topology")
+
+// QueueBinding is a work queue receiving every event of an exchange.
+type QueueBinding struct {
+    Queue    string
+    Exchange string
+}
+
+// Topology describes the queues declared next to the exchanges. Every work
+// queue Q gets two companions:
+//
+//   - Q.retry, bound to RetryExchange. Q dead-letters rejected deliveries
+//     there, and they return to Q once RetryDelay has passed.
+//   - Q.dead, bound to DeadLetterExchange. Deliveries rejected MaxRetries
+//     times, or that can never be processed, are parked there until they
+//     are replayed.
+type Topology struct {
+    Queues             []QueueBinding
+    RetryExchange      string
+    DeadLetterExchange string
+    RetryDelay         time.Duration
+    MaxRetries         int
+}
+
+// ParseQueueBindings parses a comma-separated list of queue:exchange pairs,
+// such as "counters.chat_created:chat_created".
+func ParseQueueBindings(raw string) ([]QueueBinding, error) {
+    var bindings []QueueBinding
+    for _, pair := range strings.Split(raw, ",") {
+        pair = strings.TrimSpace(pair)
+        if pair == "" {
+            continue
+        }
+        queue, exchange, ok := strings.Cut(pair, ":")
+        if !ok || queue == "" || exchange == "" {
+            return nil, fmt.Errorf("invalid queue binding %q, expected queue:exchange", pair)
+        }
+        bindings = append(bindings, QueueBinding{Queue: queue, Exchange: exchange})
+    }
+    return bindings, nil
+}
+
+func RetryQueue(queue string) string {
+    return queue + ".retry"
+}
+
+func DeadLetterQueue(queue string) string {
+    return queue + ".dead"
+}
+
+func (t Topology) hasQueue(queue string) bool {
+    for _, binding := range t.Queues {
+        if binding.Queue == queue {
+            return true
+        }
+    }
+    return false
+}
+
+// declareTopology declares the retry and dead-letter exchanges and every
+// queue with its bindings. Declaring is idempotent, but a queue that
+// already exists with different arguments makes the broker close the
+// channel.
+func declareTopology(ch *amqp.Channel, topology Topology) error {
+    for _, exchange := range []string{topology.RetryExchange, topology.DeadLetterExchange} {
+        if err := ch.ExchangeDeclare(
+            exchange, // name
+            "direct", // type
+            true,     // durable
+            false,    // auto-deleted
+            false,    // internal
+            false,    // no-wait
+            nil,      // arguments
+        ); err != nil {
+            return fmt.Errorf("failed to declare exchange %s: %w", exchange, err)
+        }
+    }
+
+    for _, binding := range topology.Queues {
+        queues := []struct {
+            name     string
+            exchange string
+            key      string
+            args     amqp.Table
+        }{
+            {binding.Queue, binding.Exchange, "#", amqp.Table{
+                "x-dead-letter-exchange":    topology.RetryExchange,
+                "x-dead-letter-routing-key": binding.Queue,
+            }},
+            {RetryQueue(binding.Queue), topology.RetryExchange, binding.Queue, amqp.Table{
+                "x-message-ttl":             topology.RetryDelay.Milliseconds(),
+                "x-dead-letter-exchange":    "",
+                "x-dead-letter-routing-key": binding.Queue,
+            }},
+            {DeadLetterQueue(binding.Queue), topology.DeadLetterExchange, binding.Queue, nil},
+        }
+
+        for _, queue := range queues {
+            if _, err := ch.QueueDeclare(
+                queue.name, // name
+                true,       // durable
+                false,      // auto-deleted
+                false,      // exclusive
+                false,      // no-wait
+                queue.args, // arguments
+            ); err != nil {
+                return fmt.Errorf("failed to declare queue %s: %w", queue.name, err)
+            }
+            if err := ch.QueueBind(queue.name, queue.key, queue.exchange, false, nil); err != nil {
+                return fmt.Errorf("failed to bind queue %s to %s: %w", queue.name, queue.exchange, err)
+            }
+        }
+    }
+
+    return nil
+}
+
+// retries returns how often d was already rejected from queue, as recorded
+// by the broker in the x-death header.
+func retries(d amqp.Delivery, queue string) int64 {
+    deaths, _ := d.Headers["x-death"].([]interface{})
+    for _, death := range deaths {
+        table, ok := death.(amqp.Table)
+        if !ok || table["queue"] != queue || table["reason"] != "rejected" {
+            continue
+        }
+        count, _ := table["count"].(int64)
+        return count
+    }
+    return 0
+}
@@ -29,5 +29,24 @@ CREATE TABLE IF NOT EXISTS messages (
     body TEXT NOT NULL,
     created_at TIMESTAMP NOT NULL,
     FOREIGN KEY (chat_id) REFERENCES chats(id),
-    UNIQUE KEY unique_chat_number (chat_id, number)
-);
\ No newline at end of file
+    UNIQUE KEY unique_chat_number (chat_id, number),
+    FULLTEXT KEY ft_body (body)
+);
+
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    event_type VARCHAR(64) NOT NULL,
+    payload JSON NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NULL,
+    created_at TIMESTAMP NOT NULL,
+    next_attempt_at TIMESTAMP NOT NULL,
+    published_at TIMESTAMP NULL,
+    KEY idx_outbox_pending (published_at, next_attempt_at)
+);
+
+CREATE TABLE IF NOT EXISTS processed_events (
+    event_key VARCHAR(128) NOT NULL PRIMARY KEY,
+    processed_at TIMESTAMP NOT NULL,
+    KEY idx_processed_at (processed_at)
+);